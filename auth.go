@@ -0,0 +1,194 @@
+package hemera
+
+import (
+	"strings"
+)
+
+const (
+	// TokenDelegateKey is the delegate field which carries the token of the caller
+	TokenDelegateKey = "token"
+	// UnauthorizedErrorName is the name of the error replied when a token is missing or invalid
+	UnauthorizedErrorName = "UnauthorizedError"
+	// UnauthorizedErrorCode is the code of the error replied when a token is missing or invalid
+	UnauthorizedErrorCode = 401
+	// ForbiddenErrorName is the name of the error replied when the claims don't satisfy the policy of the pattern
+	ForbiddenErrorName = "ForbiddenError"
+	// ForbiddenErrorCode is the code of the error replied when the claims don't satisfy the policy of the pattern
+	ForbiddenErrorCode = 403
+)
+
+type (
+	// TokenVerifier verifies a token and returns its claims e.g. *auth.Verifier
+	TokenVerifier interface {
+		Verify(token string) (map[string]interface{}, error)
+	}
+	// Claims are the verified claims of the token of a request
+	Claims map[string]interface{}
+)
+
+// VerifyTokens is an Option to verify the tokens of requests, valid claims are attached to the context of the handler
+func VerifyTokens(v TokenVerifier) Option {
+	return func(o *Options) error {
+		o.TokenVerifier = v
+		return nil
+	}
+}
+
+// AuthToken is an Option to attach the token to the delegate of every act and publish
+func AuthToken(token string) Option {
+	return TokenSource(func() (string, error) {
+		return token, nil
+	})
+}
+
+// TokenSource is an Option to attach a token to the delegate of every act and publish.
+// The token of an inherited delegate is kept.
+func TokenSource(fn func() (string, error)) Option {
+	return func(o *Options) error {
+		o.TokenSource = fn
+		return nil
+	}
+}
+
+// Authenticated is an AddOption to reject requests without a valid token with an UnauthorizedError
+func Authenticated() AddOption {
+	return func(o *AddOptions) error {
+		o.Authenticated = true
+		return nil
+	}
+}
+
+// RequireScopes is an AddOption to reject requests whose token lacks one of the scopes with a ForbiddenError.
+// Scopes are read from the `scope` claim separated by spaces or from the `scp` or `scopes` list.
+func RequireScopes(scopes ...string) AddOption {
+	return func(o *AddOptions) error {
+		o.Authenticated = true
+		o.Scopes = append(o.Scopes, scopes...)
+		return nil
+	}
+}
+
+// RequireRoles is an AddOption to reject requests whose token lacks one of the roles of the `roles` claim with a ForbiddenError
+func RequireRoles(roles ...string) AddOption {
+	return func(o *AddOptions) error {
+		o.Authenticated = true
+		o.Roles = append(o.Roles, roles...)
+		return nil
+	}
+}
+
+// NewUnauthorizedError create the error which is replied when a token is missing or invalid
+func NewUnauthorizedError(message string) *Error {
+	return NewError(UnauthorizedErrorName, message, UnauthorizedErrorCode)
+}
+
+// NewForbiddenError create the error which is replied when the claims don't satisfy the policy of the pattern
+func NewForbiddenError(message string) *Error {
+	return NewError(ForbiddenErrorName, message, ForbiddenErrorCode)
+}
+
+// IsUnauthorizedError returns true when err was caused by a missing or invalid token
+func IsUnauthorizedError(err error) bool {
+	he, ok := err.(*Error)
+	return ok && he.Name == UnauthorizedErrorName
+}
+
+// IsForbiddenError returns true when err was caused by insufficient claims
+func IsForbiddenError(err error) bool {
+	he, ok := err.(*Error)
+	return ok && he.Name == ForbiddenErrorName
+}
+
+// authenticate attaches the claims of a valid token to the context and checks the policy of the handler
+func (h *Hemera) authenticate(ctx *Context, opts *AddOptions) *Error {
+	token, _ := ctx.GetDelegate(TokenDelegateKey).(string)
+
+	if token != "" && h.Opts.TokenVerifier != nil {
+		if claims, err := h.Opts.TokenVerifier.Verify(token); err == nil {
+			ctx.Claims = Claims(claims)
+		} else if opts.Authenticated {
+			return NewUnauthorizedError("add: " + err.Error())
+		}
+	}
+
+	if !opts.Authenticated {
+		return nil
+	}
+
+	if ctx.Claims == nil {
+		return NewUnauthorizedError("add: token is required")
+	}
+
+	scopes := ctx.Claims.list("scp", "scopes")
+
+	if s, ok := ctx.Claims["scope"].(string); ok {
+		scopes = append(scopes, strings.Fields(s)...)
+	}
+
+	for _, scope := range opts.Scopes {
+		if !containsString(scopes, scope) {
+			return NewForbiddenError("add: scope " + scope + " is required")
+		}
+	}
+
+	roles := ctx.Claims.list("roles", "role")
+
+	for _, role := range opts.Roles {
+		if !containsString(roles, role) {
+			return NewForbiddenError("add: role " + role + " is required")
+		}
+	}
+
+	return nil
+}
+
+// list returns the strings of the claims, a claim is a string or a list of strings
+func (c Claims) list(names ...string) []string {
+	values := []string{}
+
+	for _, name := range names {
+		switch v := c[name].(type) {
+		case string:
+			values = append(values, v)
+		case []interface{}:
+			for _, s := range v {
+				if str, ok := s.(string); ok {
+					values = append(values, str)
+				}
+			}
+		case []string:
+			values = append(values, v...)
+		}
+	}
+
+	return values
+}
+
+// attachToken adds the token of the token source to a delegate without token
+func (h *Hemera) attachToken(delegate Delegate) (Delegate, error) {
+	if h.Opts.TokenSource == nil {
+		return delegate, nil
+	}
+
+	if token, ok := delegate[TokenDelegateKey].(string); ok && token != "" {
+		return delegate, nil
+	}
+
+	token, err := h.Opts.TokenSource()
+
+	if err != nil {
+		return delegate, NewErrorSimple("act: " + err.Error())
+	}
+
+	return Delegate(merge(delegate, map[string]interface{}{TokenDelegateKey: token})), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
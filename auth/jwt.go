@@ -0,0 +1,250 @@
+// Package auth signs and verifies JSON Web Tokens with HMAC (HS256, HS384, HS512)
+// and RSA (RS256, RS384, RS512) keys.
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	// hash functions of the algorithms
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+var (
+	// ErrMalformed is returned for tokens which are not a valid JWT
+	ErrMalformed = errors.New("auth: malformed token")
+	// ErrAlgorithm is returned when the algorithm of the token is not accepted by the verifier
+	ErrAlgorithm = errors.New("auth: unsupported algorithm")
+	// ErrSignature is returned when the signature is invalid
+	ErrSignature = errors.New("auth: invalid signature")
+	// ErrExpired is returned when the token is expired
+	ErrExpired = errors.New("auth: token is expired")
+	// ErrNotYetValid is returned when the token is used before its nbf claim
+	ErrNotYetValid = errors.New("auth: token is not valid yet")
+)
+
+var hashes = map[string]crypto.Hash{
+	"HS256": crypto.SHA256,
+	"HS384": crypto.SHA384,
+	"HS512": crypto.SHA512,
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+}
+
+type (
+	// Claims are the decoded claims of a token
+	Claims map[string]interface{}
+	// Verifier verifies tokens which are signed with its key
+	Verifier struct {
+		// Issuer and Audience are checked when they are not empty
+		Issuer   string
+		Audience string
+		// Leeway is the tolerated clock skew for the exp and nbf claims
+		Leeway  time.Duration
+		hmacKey []byte
+		rsaKey  *rsa.PublicKey
+		now     func() time.Time
+	}
+	header struct {
+		Alg string `json:"alg"`
+		Typ string `json:"typ,omitempty"`
+	}
+)
+
+// NewHMACVerifier returns a verifier of HS256, HS384 and HS512 tokens
+func NewHMACVerifier(key []byte) *Verifier {
+	return &Verifier{hmacKey: key, now: time.Now}
+}
+
+// NewRSAVerifier returns a verifier of RS256, RS384 and RS512 tokens
+func NewRSAVerifier(key *rsa.PublicKey) *Verifier {
+	return &Verifier{rsaKey: key, now: time.Now}
+}
+
+// Verify checks the signature and the registered claims of the token and returns its claims
+func (v *Verifier) Verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	h := header{}
+
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, ErrMalformed
+	}
+
+	hash, ok := hashes[h.Alg]
+
+	// the key decides the family so that a public RSA key is never used as HMAC secret
+	if !ok || (strings.HasPrefix(h.Alg, "HS") && v.hmacKey == nil) || (strings.HasPrefix(h.Alg, "RS") && v.rsaKey == nil) {
+		return nil, ErrAlgorithm
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	if err := v.verifySignature(h.Alg, hash, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	claims := Claims{}
+
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrMalformed
+	}
+
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (v *Verifier) verifySignature(alg string, hash crypto.Hash, signed string, sig []byte) error {
+	if strings.HasPrefix(alg, "HS") {
+		mac := hmac.New(hash.New, v.hmacKey)
+		mac.Write([]byte(signed))
+
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return ErrSignature
+		}
+
+		return nil
+	}
+
+	hasher := hash.New()
+	hasher.Write([]byte(signed))
+
+	if rsa.VerifyPKCS1v15(v.rsaKey, hash, hasher.Sum(nil), sig) != nil {
+		return ErrSignature
+	}
+
+	return nil
+}
+
+// validate checks the registered claims exp, nbf, iss and aud
+func (v *Verifier) validate(claims Claims) error {
+	now := v.now()
+
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(v.Leeway)) {
+		return ErrExpired
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return ErrNotYetValid
+	}
+
+	if v.Issuer != "" && claims["iss"] != v.Issuer {
+		return fmt.Errorf("auth: invalid issuer")
+	}
+
+	if v.Audience != "" && !contains(claims["aud"], v.Audience) {
+		return fmt.Errorf("auth: invalid audience")
+	}
+
+	return nil
+}
+
+// contains checks if the claim is the value or a list which contains the value
+func contains(claim interface{}, value string) bool {
+	switch c := claim.(type) {
+	case string:
+		return c == value
+	case []interface{}:
+		for _, v := range c {
+			if v == value {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// SignHMAC returns a token of the claims signed with the HMAC key, alg is HS256, HS384 or HS512
+func SignHMAC(claims map[string]interface{}, alg string, key []byte) (string, error) {
+	hash, ok := hashes[alg]
+
+	if !ok || !strings.HasPrefix(alg, "HS") {
+		return "", ErrAlgorithm
+	}
+
+	signed, err := encode(alg, claims)
+
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(hash.New, key)
+	mac.Write([]byte(signed))
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// SignRSA returns a token of the claims signed with the RSA key, alg is RS256, RS384 or RS512
+func SignRSA(claims map[string]interface{}, alg string, key *rsa.PrivateKey) (string, error) {
+	hash, ok := hashes[alg]
+
+	if !ok || !strings.HasPrefix(alg, "RS") {
+		return "", ErrAlgorithm
+	}
+
+	signed, err := encode(alg, claims)
+
+	if err != nil {
+		return "", err
+	}
+
+	hasher := hash.New()
+	hasher.Write([]byte(signed))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, hash, hasher.Sum(nil))
+
+	if err != nil {
+		return "", err
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// encode returns the encoded header and claims
+func encode(alg string, claims map[string]interface{}) (string, error) {
+	h, err := json.Marshal(header{Alg: alg, Typ: "JWT"})
+
+	if err != nil {
+		return "", err
+	}
+
+	c, err := json.Marshal(claims)
+
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c), nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHMAC(t *testing.T) {
+	assert := assert.New(t)
+
+	key := []byte("secret")
+	token, err := SignHMAC(map[string]interface{}{"sub": "u1", "exp": time.Now().Add(time.Minute).Unix()}, "HS256", key)
+	assert.Nil(err, "Should sign the token")
+
+	claims, err := NewHMACVerifier(key).Verify(token)
+	assert.Nil(err, "Should verify the token")
+	assert.Equal(claims["sub"], "u1", "Should be `u1`")
+
+	_, err = NewHMACVerifier([]byte("other")).Verify(token)
+	assert.Equal(err, ErrSignature, "Should reject another key")
+
+	parts := strings.Split(token, ".")
+	_, err = NewHMACVerifier(key).Verify(parts[0] + "." + parts[1] + "x." + parts[2])
+	assert.NotNil(err, "Should reject modified claims")
+
+	expired, _ := SignHMAC(map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()}, "HS512", key)
+	_, err = NewHMACVerifier(key).Verify(expired)
+	assert.Equal(err, ErrExpired, "Should reject expired tokens")
+
+	v := NewHMACVerifier(key)
+	v.Leeway = 2 * time.Minute
+	_, err = v.Verify(expired)
+	assert.Nil(err, "Should tolerate the leeway")
+
+	early, _ := SignHMAC(map[string]interface{}{"nbf": time.Now().Add(time.Minute).Unix()}, "HS384", key)
+	_, err = NewHMACVerifier(key).Verify(early)
+	assert.Equal(err, ErrNotYetValid, "Should reject tokens before nbf")
+
+	_, err = NewHMACVerifier(key).Verify("a.b")
+	assert.Equal(err, ErrMalformed, "Should reject malformed tokens")
+}
+
+func TestRSA(t *testing.T) {
+	assert := assert.New(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(err, "Should generate a key")
+
+	token, err := SignRSA(map[string]interface{}{"iss": "hemera", "aud": []string{"math"}}, "RS256", key)
+	assert.Nil(err, "Should sign the token")
+
+	v := NewRSAVerifier(&key.PublicKey)
+	v.Issuer = "hemera"
+	v.Audience = "math"
+
+	claims, err := v.Verify(token)
+	assert.Nil(err, "Should verify the token")
+	assert.Equal(claims["iss"], "hemera", "Should be `hemera`")
+
+	v.Audience = "user"
+	_, err = v.Verify(token)
+	assert.NotNil(err, "Should reject another audience")
+
+	hs, _ := SignHMAC(map[string]interface{}{}, "HS256", []byte("secret"))
+	_, err = NewRSAVerifier(&key.PublicKey).Verify(hs)
+	assert.Equal(err, ErrAlgorithm, "Should reject HMAC tokens with a RSA key")
+
+	none := "eyJhbGciOiJub25lIn0.e30."
+	_, err = NewHMACVerifier([]byte("secret")).Verify(none)
+	assert.Equal(err, ErrAlgorithm, "Should reject unsigned tokens")
+}
@@ -0,0 +1,56 @@
+package hemera
+
+import (
+	"testing"
+
+	"github.com/hemerajs/go-hemera/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuth(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	key := []byte("secret")
+	h := newTestHemera(t, mt, VerifyTokens(auth.NewHMACVerifier(key)))
+
+	_, err := h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply, ctx *Context) {
+		reply.Send(Response{Result: req.A + req.B})
+	}, RequireScopes("math:add"), RequireRoles("admin"))
+	assert.Nil(err, "Should add the pattern")
+
+	var claims Claims
+
+	h.Add(MathPattern{Topic: "math", Cmd: "sub"}, func(req *RequestPattern, reply Reply, ctx *Context) {
+		claims = ctx.Claims
+		reply.Send(Response{Result: req.A - req.B})
+	})
+
+	ctx := h.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, &Response{})
+	assert.True(IsUnauthorizedError(ctx.Error), "Should require a token")
+
+	ctx = h.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, &Response{}, &Context{Delegate: Delegate{TokenDelegateKey: "invalid"}})
+	assert.True(IsUnauthorizedError(ctx.Error), "Should reject an invalid token")
+
+	token, _ := auth.SignHMAC(map[string]interface{}{"sub": "u1", "scope": "math:add math:sub", "roles": []string{"user"}}, "HS256", key)
+	client := newTestHemera(t, mt, AuthToken(token))
+
+	ctx = client.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, &Response{})
+	assert.True(IsForbiddenError(ctx.Error), "Should require the role")
+	assert.Equal(ctx.Error.Error(), "add: role admin is required", "Should name the missing role")
+
+	admin, _ := auth.SignHMAC(map[string]interface{}{"sub": "u2", "scope": "math:add", "roles": []string{"admin"}}, "HS256", key)
+	res := &Response{}
+	ctx = client.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, res, &Context{Delegate: Delegate{TokenDelegateKey: admin}})
+	assert.Nil(ctx.Error, "Should pass with an inherited token")
+	assert.Equal(res.Result, 3, "Should be 3")
+
+	ctx = client.Act(RequestPattern{Topic: "math", Cmd: "sub", A: 3, B: 2}, res)
+	assert.Nil(ctx.Error, "Should not require a token")
+	assert.Equal(claims["sub"], "u1", "Should attach the claims to the context")
+
+	_, err = client.Add(MathPattern{Topic: "math", Cmd: "mul"}, func(req *RequestPattern, reply Reply) {}, Authenticated())
+	assert.Equal(err.Error(), "add: token verifier is required", "Should require a verifier")
+}
@@ -0,0 +1,526 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+const (
+	patternDirective  = "//hemera:pattern"
+	responseDirective = "//hemera:response"
+	nameDirective     = "//hemera:name"
+
+	hemeraPath = "github.com/hemerajs/go-hemera"
+)
+
+type (
+	// genPackage holds the annotated patterns of a package, Hemera is the name
+	// of the hemera package which is aliased when the source aliases it
+	genPackage struct {
+		Name     string
+		Hemera   string
+		Imports  []string
+		Patterns []genPattern
+	}
+	genPattern struct {
+		Name     string
+		Type     string
+		Response string
+		Pattern  string
+		Fields   []genField
+		Params   []genParam
+	}
+	// genField is a field with a fixed value, Value is a Go literal
+	genField struct {
+		Name  string
+		Value string
+	}
+	// genParam is a field which is passed as argument
+	genParam struct {
+		Name  string
+		Field string
+		Type  string
+	}
+)
+
+// parseDir parses the non-test files of the package, the output file is skipped
+func parseDir(dir, output string) (*genPackage, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != output
+	}, parser.ParseComments)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %s, found %d", dir, len(pkgs))
+	}
+
+	for name, p := range pkgs {
+		files := []*ast.File{}
+		names := []string{}
+
+		for fileName := range p.Files {
+			names = append(names, fileName)
+		}
+
+		// generate in a stable order
+		sort.Strings(names)
+
+		for _, fileName := range names {
+			files = append(files, p.Files[fileName])
+		}
+
+		return parseFiles(fset, name, files)
+	}
+
+	return nil, nil
+}
+
+// parseFiles collects the annotated request types of the files
+func parseFiles(fset *token.FileSet, name string, files []*ast.File) (*genPackage, error) {
+	pkg := &genPackage{Name: name, Hemera: "hemera"}
+	declared := map[string]bool{}
+	imports := map[string]bool{}
+
+	// the types of the fields decide how fixed values are written, imports are not resolved
+	// and errors are ignored, a field of an imported type falls back to its value
+	info := &types.Info{Types: map[ast.Expr]types.TypeAndValue{}}
+	conf := types.Config{Importer: noImporter{}, Error: func(error) {}}
+	conf.Check(name, fset, files, info)
+
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.TYPE {
+				for _, spec := range gd.Specs {
+					declared[spec.(*ast.TypeSpec).Name.Name] = true
+				}
+			}
+		}
+	}
+
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				doc := ts.Doc
+
+				// the comment of a single type declaration belongs to the declaration
+				if doc == nil && len(gd.Specs) == 1 {
+					doc = gd.Doc
+				}
+
+				p, used, err := parsePattern(fset, info, ts, doc)
+
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", ts.Name.Name, err)
+				}
+
+				if p == nil {
+					continue
+				}
+
+				if !declared[p.Response] {
+					return nil, fmt.Errorf("%s: response type %s is not declared", ts.Name.Name, p.Response)
+				}
+
+				for _, pkgName := range used {
+					path, err := importPath(f, pkgName)
+
+					if err != nil {
+						return nil, fmt.Errorf("%s: %v", ts.Name.Name, err)
+					}
+
+					// the generated code imports hemera itself, an alias of the source is reused
+					if strings.HasSuffix(path, strconv.Quote(hemeraPath)) {
+						pkg.Hemera = pkgName
+						continue
+					}
+
+					imports[path] = true
+				}
+
+				pkg.Patterns = append(pkg.Patterns, *p)
+			}
+		}
+	}
+
+	for path := range imports {
+		pkg.Imports = append(pkg.Imports, path)
+	}
+
+	sort.Strings(pkg.Imports)
+
+	return pkg, nil
+}
+
+// parsePattern returns the pattern of an annotated struct and the packages used by its arguments
+func parsePattern(fset *token.FileSet, info *types.Info, ts *ast.TypeSpec, doc *ast.CommentGroup) (*genPattern, []string, error) {
+	if doc == nil {
+		return nil, nil, nil
+	}
+
+	p := &genPattern{Type: ts.Name.Name}
+	fixed := map[string]string{}
+	annotated := false
+
+	for _, c := range doc.List {
+		switch {
+		case strings.HasPrefix(c.Text, patternDirective+" "):
+			annotated = true
+			p.Pattern = strings.TrimSpace(strings.TrimPrefix(c.Text, patternDirective))
+			args, err := splitArgs(p.Pattern)
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			for _, arg := range args {
+				i := strings.IndexByte(arg, '=')
+
+				if i <= 0 {
+					return nil, nil, fmt.Errorf("invalid pattern field %q", arg)
+				}
+
+				fixed[strings.ToLower(arg[:i])] = arg[i+1:]
+			}
+		case strings.HasPrefix(c.Text, responseDirective+" "):
+			p.Response = strings.TrimSpace(strings.TrimPrefix(c.Text, responseDirective))
+		case strings.HasPrefix(c.Text, nameDirective+" "):
+			p.Name = strings.TrimSpace(strings.TrimPrefix(c.Text, nameDirective))
+		}
+	}
+
+	if !annotated {
+		return nil, nil, nil
+	}
+
+	st, ok := ts.Type.(*ast.StructType)
+
+	if !ok {
+		return nil, nil, fmt.Errorf("pattern must be a struct")
+	}
+
+	if _, ok := fixed["topic"]; !ok {
+		return nil, nil, fmt.Errorf("topic is required")
+	}
+
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(strings.TrimSuffix(p.Type, "Request"), "Pattern")
+	}
+
+	if p.Name == "" || !ast.IsExported(p.Name) {
+		return nil, nil, fmt.Errorf("invalid name %q", p.Name)
+	}
+
+	if p.Response == "" {
+		p.Response = p.Name + "Response"
+	}
+
+	used := []string{}
+
+	for _, f := range st.Fields.List {
+		typ, err := exprString(fset, f.Type)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, name := range f.Names {
+			if !name.IsExported() || skipField(f) {
+				continue
+			}
+
+			if val, ok := fixed[strings.ToLower(name.Name)]; ok {
+				delete(fixed, strings.ToLower(name.Name))
+				val, err = literal(info.TypeOf(f.Type), val)
+
+				if err != nil {
+					return nil, nil, fmt.Errorf("pattern field %s: %v", name.Name, err)
+				}
+
+				p.Fields = append(p.Fields, genField{Name: name.Name, Value: val})
+				continue
+			}
+
+			p.Params = append(p.Params, genParam{Name: paramName(name.Name), Field: name.Name, Type: typ})
+			used = append(used, packages(f.Type)...)
+		}
+	}
+
+	for key := range fixed {
+		return nil, nil, fmt.Errorf("pattern field %s is not a field of the struct", key)
+	}
+
+	return p, used, nil
+}
+
+// literal returns the Go literal of a fixed value, raw is quoted when it was quoted in the directive.
+// Values of string types are quoted, values of an interface or an unresolved type are quoted unless
+// they are an unquoted number or boolean.
+func literal(t types.Type, raw string) (string, error) {
+	val, quoted := raw, strings.HasPrefix(raw, `"`)
+
+	if quoted {
+		val, _ = strconv.Unquote(raw)
+	}
+
+	var basic *types.Basic
+
+	if t != nil {
+		basic, _ = t.Underlying().(*types.Basic)
+	}
+
+	switch {
+	case t == nil || (basic != nil && basic.Kind() == types.Invalid) || types.IsInterface(t):
+		if !quoted && (val == "true" || val == "false" || isNumber(val)) {
+			return val, nil
+		}
+
+		return strconv.Quote(val), nil
+	case basic == nil:
+		return "", fmt.Errorf("unsupported type %s", t)
+	case basic.Info()&types.IsString != 0:
+		return strconv.Quote(val), nil
+	case basic.Info()&types.IsBoolean != 0 && val != "true" && val != "false":
+		return "", fmt.Errorf("%q is not a boolean", val)
+	case basic.Info()&types.IsNumeric != 0 && !isNumber(val):
+		return "", fmt.Errorf("%q is not a number", val)
+	}
+
+	return val, nil
+}
+
+// isNumber returns true for an integer or a floating point literal
+func isNumber(s string) bool {
+	e, err := parser.ParseExpr(s)
+
+	if err != nil {
+		return false
+	}
+
+	if u, ok := e.(*ast.UnaryExpr); ok && (u.Op == token.SUB || u.Op == token.ADD) {
+		e = u.X
+	}
+
+	lit, ok := e.(*ast.BasicLit)
+
+	return ok && (lit.Kind == token.INT || lit.Kind == token.FLOAT)
+}
+
+// noImporter doesn't resolve imports, the generator only needs the types of the package itself
+type noImporter struct{}
+
+func (noImporter) Import(path string) (*types.Package, error) {
+	return nil, fmt.Errorf("%s is not resolved", path)
+}
+
+// skipField returns true for fields which are never sent and for meta and delegate which are passed by the context
+func skipField(f *ast.Field) bool {
+	if f.Tag != nil {
+		tag, _ := strconv.Unquote(f.Tag.Value)
+
+		if reflect.StructTag(tag).Get("hemera") == "-" {
+			return true
+		}
+	}
+
+	if sel, ok := f.Type.(*ast.SelectorExpr); ok {
+		return sel.Sel.Name == "Meta" || sel.Sel.Name == "Delegate"
+	}
+
+	return false
+}
+
+// splitArgs splits the arguments of a directive by spaces, values can be quoted
+func splitArgs(s string) ([]string, error) {
+	args := []string{}
+
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		end := strings.IndexFunc(s, unicode.IsSpace)
+
+		if end < 0 {
+			end = len(s)
+		}
+
+		i := strings.IndexByte(s[:end], '=')
+
+		if i < 0 || i+1 == end || s[i+1] != '"' {
+			args, s = append(args, s[:end]), s[end:]
+			continue
+		}
+
+		quoted, err := strconv.QuotedPrefix(s[i+1:])
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted value in %q", s)
+		}
+
+		// the quotes are kept, a quoted value is a string
+		args, s = append(args, s[:i+1]+quoted), s[i+1+len(quoted):]
+	}
+
+	return args, nil
+}
+
+var reservedParams = map[string]bool{"ctx": true, "h": true, "req": true, "res": true, "args": true, "c": true}
+
+func paramName(field string) string {
+	r := []rune(field)
+	i := 0
+
+	// lower the leading initialism e.g. ID -> id, URLPath -> urlPath
+	for i < len(r) && unicode.IsUpper(r[i]) && (i == 0 || i+1 == len(r) || unicode.IsUpper(r[i+1])) {
+		r[i] = unicode.ToLower(r[i])
+		i++
+	}
+
+	name := string(r)
+
+	if token.Lookup(name).IsKeyword() || reservedParams[name] {
+		name += "Arg"
+	}
+
+	return name
+}
+
+func exprString(fset *token.FileSet, e ast.Expr) (string, error) {
+	buf := &bytes.Buffer{}
+
+	if err := printer.Fprint(buf, fset, e); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// packages returns the names of the packages which are referenced by the type
+func packages(e ast.Expr) []string {
+	names := []string{}
+
+	ast.Inspect(e, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				names = append(names, id.Name)
+			}
+		}
+		return true
+	})
+
+	return names
+}
+
+// importPath returns the import spec of a package name of the file
+func importPath(f *ast.File, name string) (string, error) {
+	for _, imp := range f.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+
+		if imp.Name != nil {
+			if imp.Name.Name == name {
+				return imp.Name.Name + " " + imp.Path.Value, nil
+			}
+			continue
+		}
+
+		if path == hemeraPath && name == "hemera" || path[strings.LastIndexByte(path, '/')+1:] == name {
+			return imp.Path.Value, nil
+		}
+	}
+
+	return "", fmt.Errorf("import of package %s not found", name)
+}
+
+var genTemplate = template.Must(template.New("gen").Parse(`// Code generated by hemera-gen. DO NOT EDIT.
+
+package {{.Name}}
+
+import (
+	{{if ne .Hemera "hemera"}}{{.Hemera}} {{end}}"github.com/hemerajs/go-hemera"
+{{- range .Imports}}
+	{{.}}
+{{- end}}
+)
+{{range .Patterns}}
+// {{.Name}} acts on the pattern {{.Pattern}}
+func {{.Name}}(ctx *{{$.Hemera}}.Context, h *{{$.Hemera}}.Hemera{{range .Params}}, {{.Name}} {{.Type}}{{end}}) (*{{.Response}}, error) {
+	req := {{.Type}}{
+	{{- range .Fields}}
+		{{.Name}}: {{.Value}},
+	{{- end}}
+	{{- range .Params}}
+		{{.Field}}: {{.Name}},
+	{{- end}}
+	}
+	res := &{{.Response}}{}
+	args := []interface{}{req, res}
+
+	if ctx != nil {
+		args = append(args, ctx)
+	}
+
+	if c := h.Act(args...); c.Error != nil {
+		return nil, c.Error
+	}
+
+	return res, nil
+}
+
+// Handle{{.Name}} registers the handler on the pattern {{.Pattern}}
+func Handle{{.Name}}(h *{{$.Hemera}}.Hemera, handler func(ctx *{{$.Hemera}}.Context, req *{{.Type}}) (*{{.Response}}, error), options ...{{$.Hemera}}.AddOption) ({{$.Hemera}}.Subscription, error) {
+	options = append(options, {{$.Hemera}}.Returns({{.Response}}{}))
+
+	return h.Add({{.Type}}{
+	{{- range .Fields}}
+		{{.Name}}: {{.Value}},
+	{{- end}}
+	}, func(req *{{.Type}}, reply {{$.Hemera}}.Reply, ctx *{{$.Hemera}}.Context) {
+		res, err := handler(ctx, req)
+
+		if he, ok := err.(*{{$.Hemera}}.Error); ok {
+			reply.Send(he)
+		} else if err != nil {
+			reply.Send({{$.Hemera}}.NewErrorSimple(err.Error()))
+		} else {
+			reply.Send(res)
+		}
+	}, options...)
+}
+{{end}}`))
+
+// generate returns the formatted source of the clients and registration helpers
+func generate(pkg *genPackage) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	if err := genTemplate.Execute(buf, pkg); err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid generated code: %v\n%s", err, buf.Bytes())
+	}
+
+	return src, nil
+}
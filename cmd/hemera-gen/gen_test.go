@@ -0,0 +1,181 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const source = `package math
+
+import (
+	"time"
+
+	server "github.com/hemerajs/go-hemera"
+)
+
+//hemera:pattern topic=math cmd=add
+type AddRequest struct {
+	Topic   string
+	Cmd     string
+	A       int
+	B       int
+	Timeout time.Duration
+	Secret  string ` + "`hemera:\"-\"`" + `
+	Meta    server.Meta
+}
+
+type AddResponse struct {
+	Result int
+}
+
+// SumPattern sums a list
+//hemera:pattern topic=math cmd="sum all" version=2
+//hemera:name Sum
+//hemera:response AddResponse
+type SumPattern struct {
+	Topic   string
+	Cmd     string
+	Version int
+	Type    []int
+	Parent  *server.Trace
+}
+`
+
+func TestGenerate(t *testing.T) {
+	assert := assert.New(t)
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "math.go", source, parser.ParseComments)
+	assert.Nil(err, "Should parse the source")
+
+	pkg, err := parseFiles(fset, "math", []*ast.File{f})
+	assert.Nil(err, "Should find the patterns")
+	assert.Equal(len(pkg.Patterns), 2, "Should be 2")
+	assert.Equal(pkg.Imports, []string{`"time"`}, "Should import the packages of the arguments")
+	assert.Equal(pkg.Hemera, "server", "Should reuse the alias of hemera")
+
+	add := pkg.Patterns[0]
+	assert.Equal(add.Name, "Add", "Should trim the suffix")
+	assert.Equal(add.Response, "AddResponse", "Should be the default response")
+	assert.Equal(add.Fields, []genField{{"Topic", `"math"`}, {"Cmd", `"add"`}}, "Should be the fixed fields")
+	assert.Equal(add.Params, []genParam{{"a", "A", "int"}, {"b", "B", "int"}, {"timeout", "Timeout", "time.Duration"}}, "Should skip meta and unsent fields")
+
+	sum := pkg.Patterns[1]
+	assert.Equal(sum.Name, "Sum", "Should be the name of the directive")
+	assert.Equal(sum.Fields, []genField{{"Topic", `"math"`}, {"Cmd", `"sum all"`}, {"Version", "2"}}, "Should unquote values")
+	assert.Equal(sum.Params, []genParam{{"typeArg", "Type", "[]int"}, {"parent", "Parent", "*server.Trace"}}, "Should rename keywords")
+
+	src, err := generate(pkg)
+	assert.Nil(err, "Should generate valid code")
+	assert.Contains(string(src), "func Add(ctx *server.Context, h *server.Hemera, a int, b int, timeout time.Duration) (*AddResponse, error) {", "Should generate the client")
+	assert.Contains(string(src), "func HandleSum(h *server.Hemera, handler func(ctx *server.Context, req *SumPattern) (*AddResponse, error), options ...server.AddOption) (server.Subscription, error) {", "Should generate the registration")
+	assert.Nil(typeCheck(fset, f, src), "Should compile with the source")
+
+	unaliased := strings.NewReplacer(`server "`, `"`, "server.", "hemera.").Replace(source)
+	f, err = parser.ParseFile(fset, "math.go", unaliased, parser.ParseComments)
+	assert.Nil(err, "Should parse the source")
+
+	pkg, err = parseFiles(fset, "math", []*ast.File{f})
+	assert.Nil(err, "Should find the patterns")
+	assert.Equal(pkg.Hemera, "hemera", "Should be the name of the package")
+	assert.Equal(pkg.Imports, []string{`"time"`}, "Should not import hemera twice")
+
+	src, err = generate(pkg)
+	assert.Nil(err, "Should generate valid code")
+	assert.Nil(typeCheck(fset, f, src), "Should compile with the source")
+
+	_, err = parseFiles(fset, "math", []*ast.File{parse(t, "//hemera:pattern cmd=add\ntype A struct{ Cmd string }\ntype AResponse struct{}")})
+	assert.EqualError(err, "A: topic is required", "Should require the topic")
+
+	_, err = parseFiles(fset, "math", []*ast.File{parse(t, "//hemera:pattern topic=math\ntype BRequest struct{ Topic string }")})
+	assert.EqualError(err, "BRequest: response type BResponse is not declared", "Should require the response type")
+}
+
+const namedSource = `package math
+
+import "time"
+
+type Cmd string
+
+//hemera:pattern topic=math cmd=mul version=2 scale=1.5 strict=true label="2" kind=product timeout=5
+type MulRequest struct {
+	Topic   Cmd
+	Cmd     Cmd
+	Version interface{}
+	Scale   float64
+	Strict  bool
+	Label   interface{}
+	Kind    interface{}
+	Timeout time.Duration
+	A       int
+}
+
+type MulResponse struct {
+	Result int
+}
+`
+
+func TestGenerateFieldTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "math.go", namedSource, parser.ParseComments)
+	assert.Nil(err, "Should parse the source")
+
+	pkg, err := parseFiles(fset, "math", []*ast.File{f})
+	assert.Nil(err, "Should find the pattern")
+	assert.Equal(pkg.Patterns[0].Fields, []genField{
+		{"Topic", `"math"`},
+		{"Cmd", `"mul"`},
+		{"Version", "2"},
+		{"Scale", "1.5"},
+		{"Strict", "true"},
+		{"Label", `"2"`},
+		{"Kind", `"product"`},
+		{"Timeout", "5"},
+	}, "Should quote values of named string types and strings of interfaces")
+
+	src, err := generate(pkg)
+	assert.Nil(err, "Should generate valid code")
+	assert.Nil(typeCheck(fset, f, src), "Should compile with the source")
+
+	_, err = parseFiles(fset, "math", []*ast.File{parse(t, "//hemera:pattern topic=math strict=yes\ntype ARequest struct{ Topic string; Strict bool }\ntype AResponse struct{}")})
+	assert.EqualError(err, `ARequest: pattern field Strict: "yes" is not a boolean`, "Should reject invalid booleans")
+
+	_, err = parseFiles(fset, "math", []*ast.File{parse(t, "//hemera:pattern topic=math a=x\ntype ARequest struct{ Topic string; A int }\ntype AResponse struct{}")})
+	assert.EqualError(err, `ARequest: pattern field A: "x" is not a number`, "Should reject invalid numbers")
+
+	_, err = parseFiles(fset, "math", []*ast.File{parse(t, "//hemera:pattern topic=math a=1\ntype ARequest struct{ Topic string; A []int }\ntype AResponse struct{}")})
+	assert.EqualError(err, "ARequest: pattern field A: unsupported type []int", "Should reject composite types")
+}
+
+func parse(t *testing.T, src string) *ast.File {
+	f, err := parser.ParseFile(token.NewFileSet(), "test.go", "package math\n"+src, parser.ParseComments)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+// typeCheck checks the generated source together with the annotated file, hemera is imported from source
+func typeCheck(fset *token.FileSet, f *ast.File, src []byte) error {
+	gen, err := parser.ParseFile(fset, "hemera_gen.go", src, 0)
+
+	if err != nil {
+		return err
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = conf.Check("math", fset, []*ast.File{f, gen}, nil)
+
+	return err
+}
@@ -0,0 +1,65 @@
+// Command hemera-gen generates typed clients and registration helpers for annotated patterns.
+//
+// A request struct is annotated with the fixed fields of its pattern, all other
+// fields become the arguments of the client:
+//
+//	//hemera:pattern topic=math cmd=add
+//	//hemera:response AddResponse
+//	type AddRequest struct {
+//		Topic string
+//		Cmd   string
+//		A     int
+//		B     int
+//	}
+//
+// For the example above hemera-gen generates
+//
+//	func Add(ctx *hemera.Context, h *hemera.Hemera, a int, b int) (*AddResponse, error)
+//	func HandleAdd(h *hemera.Hemera, handler func(ctx *hemera.Context, req *AddRequest) (*AddResponse, error), options ...hemera.AddOption) (hemera.Subscription, error)
+//
+// The name is the type name without a Request or Pattern suffix and can be set
+// with `//hemera:name Sum`. Without a response directive the type <Name>Response is used.
+//
+// Usage:
+//
+//	//go:generate hemera-gen -output hemera_gen.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "directory of the package")
+	output := flag.String("output", "hemera_gen.go", "file name of the generated code in the directory")
+	flag.Parse()
+
+	if err := run(*dir, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "hemera-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, output string) error {
+	pkg, err := parseDir(dir, output)
+
+	if err != nil {
+		return err
+	}
+
+	if len(pkg.Patterns) == 0 {
+		return fmt.Errorf("no annotated patterns in %s", dir)
+	}
+
+	src, err := generate(pkg)
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, output), src, 0644)
+}
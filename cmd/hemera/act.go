@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/hemerajs/go-hemera"
+	jsoniter "github.com/json-iterator/go"
+)
+
+func runAct(args []string) error {
+	fs := flag.NewFlagSet("act", flag.ExitOnError)
+	url, timeout := connFlags(fs)
+	meta := fs.String("meta", "", "meta as JSON object")
+	delegate := fs.String("delegate", "", "delegate as JSON object")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return errors.New("act: pattern is required")
+	}
+
+	pattern, err := parsePattern(fs.Arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	ctx := &hemera.Context{}
+
+	if err := parseObject(*meta, &ctx.Meta); err != nil {
+		return fmt.Errorf("act: invalid meta: %v", err)
+	}
+
+	if err := parseObject(*delegate, &ctx.Delegate); err != nil {
+		return fmt.Errorf("act: invalid delegate: %v", err)
+	}
+
+	h, err := connect(*url, *timeout)
+
+	if err != nil {
+		return err
+	}
+
+	defer h.Transport.Close()
+
+	var out interface{}
+	res := h.Act(pattern, &out, ctx)
+
+	if res.Error != nil {
+		return res.Error
+	}
+
+	printJSON("result", out)
+
+	if len(res.Meta) > 0 {
+		printJSON("meta", res.Meta)
+	}
+
+	return nil
+}
+
+// parsePattern decode a JSON pattern which must contain a topic
+func parsePattern(s string) (map[string]interface{}, error) {
+	pattern := map[string]interface{}{}
+
+	if err := jsoniter.UnmarshalFromString(s, &pattern); err != nil {
+		return nil, fmt.Errorf("invalid pattern: %v", err)
+	}
+
+	for key := range pattern {
+		if strings.ToLower(key) == "topic" {
+			return pattern, nil
+		}
+	}
+
+	return nil, errors.New("invalid pattern: topic is required")
+}
+
+func parseObject(s string, v interface{}) error {
+	if s == "" {
+		return nil
+	}
+
+	return jsoniter.UnmarshalFromString(s, v)
+}
+
+func printJSON(label string, v interface{}) {
+	data, err := jsoniter.MarshalIndent(v, "", "  ")
+
+	if err != nil {
+		fmt.Printf("%s: %v\n", label, v)
+		return
+	}
+
+	fmt.Printf("%s: %s\n", label, data)
+}
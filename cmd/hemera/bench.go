@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	url, timeout := connFlags(fs)
+	n := fs.Int("n", 1000, "number of requests")
+	c := fs.Int("c", 10, "number of concurrent callers")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return errors.New("bench: pattern is required")
+	}
+
+	if *n < 1 || *c < 1 {
+		return errors.New("bench: n and c must be positive")
+	}
+
+	pattern, err := parsePattern(fs.Arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	h, err := connect(*url, *timeout)
+
+	if err != nil {
+		return err
+	}
+
+	defer h.Transport.Close()
+
+	latencies := make([]time.Duration, *n)
+	failures := make([]bool, *n)
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+
+	start := time.Now()
+
+	for w := 0; w < *c; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				var out interface{}
+				t := time.Now()
+				ctx := h.Act(pattern, &out)
+				latencies[i] = time.Since(t)
+				failures[i] = ctx.Error != nil
+			}
+		}()
+	}
+
+	for i := 0; i < *n; i++ {
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	errs := 0
+
+	for _, failed := range failures {
+		if failed {
+			errs++
+		}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	fmt.Printf("requests:    %d (%d errors)\n", *n, errs)
+	fmt.Printf("concurrency: %d\n", *c)
+	fmt.Printf("duration:    %v\n", elapsed)
+	fmt.Printf("throughput:  %.1f req/s\n", float64(*n)/elapsed.Seconds())
+
+	for _, p := range []float64{50, 90, 95, 99} {
+		fmt.Printf("p%-10v %v\n", p, percentile(latencies, p))
+	}
+
+	fmt.Printf("max         %v\n", latencies[len(latencies)-1])
+
+	return nil
+}
+
+// percentile returns the p-th percentile of the sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted))*p/100+0.5) - 1
+
+	if i < 0 {
+		i = 0
+	}
+
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+
+	return sorted[i]
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"github.com/hemerajs/go-hemera"
+	jsoniter "github.com/json-iterator/go"
+	nats "github.com/nats-io/go-nats"
+)
+
+type contractResponse struct {
+	Result hemera.Contract `json:"result"`
+	Error  *hemera.Error   `json:"error"`
+}
+
+func runContract(args []string) error {
+	fs := flag.NewFlagSet("contract", flag.ExitOnError)
+	url, timeout := connFlags(fs)
+	fs.Parse(args)
+
+	nc, err := nats.Connect(*url)
+
+	if err != nil {
+		return err
+	}
+
+	defer nc.Close()
+
+	contracts := []hemera.Contract{}
+
+	err = collect(nc, hemera.ContractTopic, time.Duration(*timeout)*time.Millisecond, func(data []byte) {
+		res := contractResponse{}
+
+		if err := jsoniter.Unmarshal(data, &res); err != nil || res.Error != nil {
+			return
+		}
+
+		contracts = append(contracts, res.Result)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	enc := jsoniter.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(contracts)
+}
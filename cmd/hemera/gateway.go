@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hemerajs/go-hemera/gateway"
+	jsoniter "github.com/json-iterator/go"
+)
+
+func runGateway(args []string) error {
+	fs := flag.NewFlagSet("gateway", flag.ExitOnError)
+	url, timeout := connFlags(fs)
+	listen := fs.String("listen", ":8080", "HTTP listen address")
+	routes := fs.String("routes", "", "JSON file with an array of routes {method, path, pattern}")
+	actPath := fs.String("act-path", "/act", "path of the generic endpoint which accepts a JSON pattern, empty disables it")
+	fs.Parse(args)
+
+	options := []gateway.Option{gateway.ActPath(*actPath)}
+
+	if *routes != "" {
+		data, err := ioutil.ReadFile(*routes)
+
+		if err != nil {
+			return err
+		}
+
+		table := []gateway.Route{}
+
+		if err := jsoniter.Unmarshal(data, &table); err != nil {
+			return fmt.Errorf("gateway: invalid routes: %v", err)
+		}
+
+		options = append(options, gateway.Routes(table))
+	}
+
+	h, err := connect(*url, *timeout)
+
+	if err != nil {
+		return err
+	}
+
+	defer h.Transport.Close()
+
+	g, err := gateway.New(h, options...)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("gateway listening on %s\n", *listen)
+
+	return http.ListenAndServe(*listen, g)
+}
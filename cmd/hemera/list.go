@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/hemerajs/go-hemera"
+	jsoniter "github.com/json-iterator/go"
+	nats "github.com/nats-io/go-nats"
+	"github.com/nats-io/nuid"
+)
+
+type listResponse struct {
+	Result hemera.PatternList `json:"result"`
+}
+
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	url, timeout := connFlags(fs)
+	fs.Parse(args)
+
+	nc, err := nats.Connect(*url)
+
+	if err != nil {
+		return err
+	}
+
+	defer nc.Close()
+
+	instances := 0
+
+	err = collect(nc, hemera.ListTopic, time.Duration(*timeout)*time.Millisecond, func(data []byte) {
+		res := listResponse{}
+
+		if err := jsoniter.Unmarshal(data, &res); err != nil {
+			return
+		}
+
+		instances++
+		fmt.Printf("instance %s\n", res.Result.ID)
+
+		for _, p := range res.Result.Patterns {
+			data, _ := jsoniter.Marshal(p)
+			fmt.Printf("  %s\n", data)
+		}
+	})
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d instance(s)\n", instances)
+
+	return nil
+}
+
+// collect sends a request to all instances and passes every answer to fn until the timeout expired
+func collect(nc *nats.Conn, topic string, timeout time.Duration, fn func(data []byte)) error {
+	inbox := nats.NewInbox()
+	sub, err := nc.SubscribeSync(inbox)
+
+	if err != nil {
+		return err
+	}
+
+	defer sub.Unsubscribe()
+
+	data, _ := jsoniter.Marshal(map[string]interface{}{
+		"request": map[string]string{"id": nuid.Next(), "type": hemera.RequestType},
+	})
+
+	if err := nc.PublishRequest(topic, inbox, data); err != nil {
+		return err
+	}
+
+	// every instance answers, collect until the timeout expired
+	deadline := time.Now().Add(timeout)
+
+	for {
+		m, err := sub.NextMsg(time.Until(deadline))
+
+		if err == nats.ErrTimeout {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		fn(m.Data)
+	}
+}
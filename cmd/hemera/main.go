@@ -0,0 +1,87 @@
+// Command hemera is a command-line tool to interact with hemera services.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/hemerajs/go-hemera"
+	nats "github.com/nats-io/go-nats"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"act":      {"act [flags] <pattern>    send a JSON pattern and print the result", runAct},
+	"list":     {"list [flags]             list the registered patterns of all instances", runList},
+	"contract": {"contract [flags]         print the endpoints of all instances as JSON", runContract},
+	"gateway":  {"gateway [flags]          serve HTTP requests as acts", runGateway},
+	"watch":    {"watch [flags] <topic>    print the decoded packets of a subject", runWatch},
+	"bench":    {"bench [flags] <pattern>  load-test a pattern and print latency percentiles", runBench},
+	"replay":   {"replay [flags] <file>    re-issue recorded acts and diff the responses", runReplay},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "hemera: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	names := []string{}
+
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "Usage: hemera <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+}
+
+// connFlags register the connection flags shared by all commands
+func connFlags(fs *flag.FlagSet) (*string, *int) {
+	url := fs.String("server", nats.DefaultURL, "NATS server url")
+	timeout := fs.Int("timeout", hemera.RequestTimeout, "request timeout in milliseconds")
+	return url, timeout
+}
+
+func connect(url string, timeout int) (*hemera.Hemera, error) {
+	nc, err := nats.Connect(url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := hemera.CreateHemera(nc, hemera.Timeout(time.Duration(timeout)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &h, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/hemerajs/go-hemera/record"
+)
+
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	url, timeout := connFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return errors.New("replay: recording file is required")
+	}
+
+	entries, err := record.ReadFile(fs.Arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	h, err := connect(*url, *timeout)
+
+	if err != nil {
+		return err
+	}
+
+	defer h.Transport.Close()
+
+	failed := 0
+
+	for _, res := range record.Replay(h, entries) {
+		switch {
+		case res.Error != nil:
+			failed++
+			fmt.Printf("FAIL %s #%d: %v\n", res.Request.Subject, res.Request.ID, res.Error)
+		case len(res.Diff) > 0:
+			failed++
+			fmt.Printf("DIFF %s #%d\n", res.Request.Subject, res.Request.ID)
+
+			for _, d := range res.Diff {
+				fmt.Printf("     %s\n", d)
+			}
+		default:
+			fmt.Printf("OK   %s #%d\n", res.Request.Subject, res.Request.ID)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("replay: %d of the requests differ", failed)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	nats "github.com/nats-io/go-nats"
+)
+
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	url, _ := connFlags(fs)
+	replies := fs.Bool("replies", false, "also print the responses which are sent to inboxes")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return errors.New("watch: topic is required")
+	}
+
+	nc, err := nats.Connect(*url)
+
+	if err != nil {
+		return err
+	}
+
+	defer nc.Close()
+
+	show := func(m *nats.Msg) {
+		fmt.Printf("%s %s", time.Now().Format("15:04:05.000"), m.Subject)
+
+		if m.Reply != "" {
+			fmt.Printf(" (reply %s)", m.Reply)
+		}
+
+		fmt.Println()
+
+		out := &bytes.Buffer{}
+
+		if err := json.Indent(out, m.Data, "  ", "  "); err != nil {
+			fmt.Printf("  %s\n", m.Data)
+			return
+		}
+
+		fmt.Printf("  %s\n", out.Bytes())
+	}
+
+	if _, err := nc.Subscribe(fs.Arg(0), show); err != nil {
+		return err
+	}
+
+	if *replies {
+		if _, err := nc.Subscribe("_INBOX.>", show); err != nil {
+			return err
+		}
+	}
+
+	// wait for ctrl-c
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	<-sig
+
+	return nil
+}
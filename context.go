@@ -1,8 +1,117 @@
 package hemera
 
+// Context carries the meta, delegate and trace of an act.
+//
+// Delegate travels down the whole call chain: a nested Act or Publish with the
+// context of a handler inherits its delegate. Meta belongs to a single hop: the
+// meta of a request is not forwarded by nested acts, response meta set by the
+// handler is returned to the caller merged with the meta of the request. Meta
+// and delegate of the pattern are merged into the inherited values and win.
+//
+// In a handler Meta and Delegate only hold the values which were set by the handler.
+// The meta and delegate of the request can't be modified, they are read with
+// GetMeta, GetDelegate, CopyMeta and CopyDelegate.
 type Context struct {
 	Meta     Meta
 	Delegate Delegate
 	Trace    Trace
 	Error    error
+	// Claims of a verified token of the request
+	Claims Claims
+	// the context of a handler, its meta is not forwarded
+	incoming bool
+	// meta and delegate of the request
+	meta     Meta
+	delegate Delegate
+}
+
+// GetMeta returns a value of the meta, values set by a handler win over the request
+func (c *Context) GetMeta(key string) interface{} {
+	if val, ok := c.Meta[key]; ok {
+		return val
+	}
+
+	return c.meta[key]
+}
+
+// SetMeta sets a value of the meta, in a handler the value is returned to the caller
+func (c *Context) SetMeta(key string, value interface{}) {
+	if c.Meta == nil {
+		c.Meta = Meta{}
+	}
+
+	c.Meta[key] = value
+}
+
+// GetDelegate returns a value of the delegate, values set by a handler win over the request
+func (c *Context) GetDelegate(key string) interface{} {
+	if val, ok := c.Delegate[key]; ok {
+		return val
+	}
+
+	return c.delegate[key]
+}
+
+// SetDelegate sets a value of the delegate, in a handler the value is passed to nested acts
+func (c *Context) SetDelegate(key string, value interface{}) {
+	if c.Delegate == nil {
+		c.Delegate = Delegate{}
+	}
+
+	c.Delegate[key] = value
+}
+
+// CopyMeta returns a copy of the meta of the request merged with the values set by a handler
+func (c *Context) CopyMeta() Meta {
+	return Meta(merge(c.meta, c.Meta))
+}
+
+// CopyDelegate returns a copy of the delegate of the request merged with the values set by a handler
+func (c *Context) CopyDelegate() Delegate {
+	return Delegate(merge(c.delegate, c.Delegate))
+}
+
+// newIncomingContext returns the context of a handler for the request
+func newIncomingContext(pack *packet) *Context {
+	// handlers can set response meta and delegate for nested acts
+	return &Context{
+		Trace:    pack.Trace,
+		Meta:     Meta{},
+		Delegate: Delegate{},
+		incoming: true,
+		meta:     pack.Meta,
+		delegate: pack.Delegate,
+	}
+}
+
+// propagate returns the meta and delegate of an outgoing act with the optional context
+func propagate(ctx *Context, meta Meta, delegate Delegate) (Meta, Delegate) {
+	if ctx == nil {
+		return meta, delegate
+	}
+
+	if !ctx.incoming {
+		meta = Meta(merge(ctx.Meta, meta))
+	}
+
+	return meta, Delegate(merge(ctx.CopyDelegate(), delegate))
+}
+
+// merge returns a copy of base with the values of override
+func merge(base, override map[string]interface{}) map[string]interface{} {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+
+	m := make(map[string]interface{}, len(base)+len(override))
+
+	for key, val := range base {
+		m[key] = val
+	}
+
+	for key, val := range override {
+		m[key] = val
+	}
+
+	return m
 }
@@ -0,0 +1,85 @@
+package hemera
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ChainPattern struct {
+	Topic string
+	Cmd   string
+	Meta  Meta
+}
+
+func TestContextPropagation(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h := newTestHemera(t, mt)
+
+	var inner *Context
+
+	h.Add(MathPattern{Topic: "inner", Cmd: "get"}, func(req *ChainPattern, reply Reply, ctx *Context) {
+		inner = ctx
+		ctx.SetMeta("inner", true)
+		reply.Send(Response{Result: 1})
+	})
+
+	h.Add(MathPattern{Topic: "outer", Cmd: "get"}, func(req *ChainPattern, reply Reply, ctx *Context) {
+		ctx.SetDelegate("hop", "outer")
+
+		res := &Response{}
+		nested := h.Act(ChainPattern{Topic: "inner", Cmd: "get", Meta: Meta{"hop": 2}}, res, ctx)
+
+		ctx.SetMeta("nested", nested.GetMeta("inner"))
+		reply.Send(res)
+	})
+
+	ctx := h.Act(MathPattern{Topic: "outer", Cmd: "get"}, &Response{}, &Context{
+		Meta:     Meta{"tenant": "a"},
+		Delegate: Delegate{"user": "u1"},
+	})
+
+	assert.Nil(ctx.Error, "Should have no error")
+	assert.Equal(inner.GetDelegate("user"), "u1", "Should inherit the delegate of the caller")
+	assert.Equal(inner.GetDelegate("hop"), "outer", "Should inherit the delegate set by the handler")
+	assert.Nil(inner.GetMeta("tenant"), "Should not forward the meta of the request")
+	assert.Equal(inner.GetMeta("hop"), float64(2), "Should send the meta of the pattern")
+
+	assert.Equal(ctx.CopyMeta(), Meta{"tenant": "a", "nested": true}, "Should return the meta of the request merged with the response meta")
+	assert.Equal(ctx.CopyDelegate(), Delegate{"user": "u1"}, "Should keep the delegate of the caller")
+
+	copied := ctx.CopyMeta()
+	copied["tenant"] = "b"
+	assert.Equal(ctx.GetMeta("tenant"), "a", "Should not modify the context")
+}
+
+func TestIncomingContext(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h := newTestHemera(t, mt)
+
+	h.Add(MathPattern{Topic: "math", Cmd: "get"}, func(req *ChainPattern, reply Reply, ctx *Context) {
+		delete(ctx.Meta, "tenant")
+		delete(ctx.Delegate, "user")
+		ctx.CopyMeta()["tenant"] = "b"
+
+		reply.Send([]interface{}{ctx.GetMeta("tenant"), ctx.GetDelegate("user"), len(ctx.Meta)})
+	})
+
+	var res []interface{}
+	ctx := h.Act(MathPattern{Topic: "math", Cmd: "get"}, &res, &Context{
+		Meta:     Meta{"tenant": "a"},
+		Delegate: Delegate{"user": "u1"},
+	})
+
+	assert.Nil(ctx.Error, "Should have no error")
+	assert.Equal(res, []interface{}{"a", "u1", 0.0}, "Should not expose the meta and delegate of the request for modification")
+	assert.Equal(ctx.GetMeta("tenant"), "a", "Should return the meta of the request")
+}
@@ -0,0 +1,168 @@
+package hemera
+
+import (
+	"reflect"
+
+	"github.com/hemerajs/go-hemera/router"
+	"github.com/hemerajs/go-hemera/schema"
+)
+
+// ContractTopic is the subject on which every instance answers with its contract
+const ContractTopic = "hemera.contract"
+
+type (
+	// Contract describes the endpoints of an instance
+	Contract struct {
+		ID        string     `json:"id"`
+		Endpoints []Endpoint `json:"endpoints"`
+	}
+	// Endpoint describes a registered pattern, the schemas of its request and response and its errors
+	Endpoint struct {
+		Topic       string                        `json:"topic"`
+		Pattern     router.PatternFields          `json:"pattern"`
+		Matchers    map[string]router.MatcherSpec `json:"matchers,omitempty"`
+		Priority    int                           `json:"priority,omitempty"`
+		Fallback    bool                          `json:"fallback,omitempty"`
+		Handler     string                        `json:"handler,omitempty"`
+		Description string                        `json:"description,omitempty"`
+		Request     *schema.Schema                `json:"request,omitempty"`
+		Response    *schema.Schema                `json:"response,omitempty"`
+		Errors      []ContractError               `json:"errors,omitempty"`
+	}
+	// ContractError is an error which can be replied by an endpoint
+	ContractError struct {
+		Name    string `json:"name"`
+		Code    int16  `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// Description is an AddOption to describe the pattern in the contract
+func Description(text string) AddOption {
+	return func(o *AddOptions) error {
+		o.Description = text
+		return nil
+	}
+}
+
+// Returns is an AddOption to declare the type of the response in the contract
+func Returns(v interface{}) AddOption {
+	return func(o *AddOptions) error {
+		if v == nil {
+			return NewErrorSimple("add: response type is required")
+		}
+
+		o.ResponseType = reflect.TypeOf(v)
+		return nil
+	}
+}
+
+// Errors is an AddOption to declare the errors which are replied by the handler
+func Errors(errs ...*Error) AddOption {
+	return func(o *AddOptions) error {
+		o.Errors = append(o.Errors, errs...)
+		return nil
+	}
+}
+
+// Contract returns the endpoints of all patterns and fallbacks by insertion order.
+// Patterns without a handler of the instance are skipped.
+func (h *Hemera) Contract() (Contract, error) {
+	c := Contract{ID: h.ID, Endpoints: []Endpoint{}}
+
+	for _, ps := range h.Router.List() {
+		hd, ok := ps.Payload.(*handler)
+
+		if !ok {
+			continue
+		}
+
+		e, err := endpoint(ps, hd)
+
+		if err != nil {
+			return c, err
+		}
+
+		c.Endpoints = append(c.Endpoints, e)
+	}
+
+	if h.fallbacks == nil {
+		return c, nil
+	}
+
+	for _, ps := range h.fallbacks.List() {
+		hd, ok := ps.Payload.(*handler)
+
+		if !ok {
+			continue
+		}
+
+		e, err := endpoint(ps, hd)
+
+		if err != nil {
+			return c, err
+		}
+
+		e.Fallback = true
+		c.Endpoints = append(c.Endpoints, e)
+	}
+
+	return c, nil
+}
+
+func endpoint(ps *router.PatternSet, hd *handler) (Endpoint, error) {
+	te, err := ps.TableEntry()
+
+	if err != nil {
+		return Endpoint{}, err
+	}
+	topic, _ := ps.Fields["topic"].(string)
+
+	e := Endpoint{
+		Topic:       topic,
+		Pattern:     te.Fields,
+		Matchers:    te.Matchers,
+		Priority:    te.Priority,
+		Handler:     te.Handler,
+		Description: hd.opts.Description,
+		Request:     hd.opts.Schema,
+	}
+
+	if e.Request == nil {
+		if e.Request, err = schema.Reflect(hd.argType); err != nil {
+			return e, err
+		}
+	}
+
+	if hd.opts.ResponseType != nil {
+		if e.Response, err = schema.Reflect(hd.opts.ResponseType); err != nil {
+			return e, err
+		}
+	}
+
+	if hd.opts.Schema != nil {
+		e.Errors = append(e.Errors, ContractError{Name: ValidationErrorName, Code: ValidationErrorCode})
+	}
+
+	if hd.opts.Authenticated {
+		e.Errors = append(e.Errors, ContractError{Name: UnauthorizedErrorName, Code: UnauthorizedErrorCode})
+	}
+
+	if len(hd.opts.Scopes) > 0 || len(hd.opts.Roles) > 0 {
+		e.Errors = append(e.Errors, ContractError{Name: ForbiddenErrorName, Code: ForbiddenErrorCode})
+	}
+
+	if hd.opts.Encrypted || hd.opts.Signed {
+		e.Errors = append(e.Errors, ContractError{Name: SecurityErrorName, Code: SecurityErrorCode})
+	}
+
+	if hd.opts.RateLimiter != nil {
+		e.Errors = append(e.Errors, ContractError{Name: RateLimitErrorName, Code: RateLimitErrorCode})
+	}
+
+	for _, he := range hd.opts.Errors {
+		e.Errors = append(e.Errors, ContractError{Name: he.Name, Code: he.Code, Message: he.Message})
+	}
+
+	return e, nil
+}
@@ -0,0 +1,51 @@
+package hemera
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hemerajs/go-hemera/router"
+	"github.com/hemerajs/go-hemera/schema"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContract(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h := newTestHemera(t, mt)
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *ValidatedPattern, reply Reply) {},
+		ValidateTags(),
+		Description("adds two numbers"),
+		Returns(Response{}),
+		Errors(NewError("OverflowError", "result overflows", 422)),
+	)
+	h.Fallback("math", func(req *RequestPattern, reply Reply) {})
+
+	m, err := mt.Request(ContractTopic, []byte("{}"), time.Second)
+	assert.Nil(err, "Should answer contract requests")
+
+	res := struct {
+		Result Contract `json:"result"`
+	}{}
+	jsoniter.Unmarshal(m.Data, &res)
+
+	assert.Equal(res.Result.ID, h.ID, "Should contain the instance id")
+	assert.Equal(len(res.Result.Endpoints), 2, "Should contain the pattern and the fallback")
+
+	e := res.Result.Endpoints[0]
+	assert.Equal(e.Topic, "math", "Should be `math`")
+	assert.Equal(e.Pattern, router.PatternFields{"topic": "math", "cmd": "add"}, "Should contain the fields of the pattern")
+	assert.Equal(e.Description, "adds two numbers", "Should contain the description")
+	assert.Equal(e.Request.Required, []string{"A"}, "Should contain the request schema")
+	assert.Equal(e.Response.Properties["Result"].Type, schema.TypeInteger, "Should contain the response schema")
+	assert.Equal(e.Errors, []ContractError{
+		{Name: ValidationErrorName, Code: ValidationErrorCode},
+		{Name: "OverflowError", Code: 422, Message: "result overflows"},
+	}, "Should contain all errors")
+	assert.True(res.Result.Endpoints[1].Fallback, "Should be a fallback")
+}
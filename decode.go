@@ -0,0 +1,200 @@
+package hemera
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/hemerajs/go-hemera/schema"
+	"github.com/mitchellh/mapstructure"
+)
+
+type (
+	// ActOption is a function on the options of a single act, it is passed after the result
+	ActOption  func(*ActOptions) error
+	ActOptions struct {
+		// StrictResult rejects unknown fields and lossy conversions of the result
+		StrictResult bool
+		ResultSchema *schema.Schema
+		// the schema is reflected from the `validate` tags of the result type
+		ValidateResult bool
+		// the request is encrypted with the keyring
+		Encrypt bool
+	}
+)
+
+// StrictDecoding is an Option to decode the results of all acts strictly
+func StrictDecoding(strict bool) Option {
+	return func(o *Options) error {
+		o.StrictDecoding = strict
+		return nil
+	}
+}
+
+// StrictResult is an ActOption to reject results with unknown fields, mismatched types or fractions for integers
+func StrictResult() ActOption {
+	return func(o *ActOptions) error {
+		o.StrictResult = true
+		return nil
+	}
+}
+
+// ResultSchema is an ActOption to reject results which violate the schema
+func ResultSchema(s *schema.Schema) ActOption {
+	return func(o *ActOptions) error {
+		if s == nil {
+			return NewErrorSimple("act: schema is required")
+		}
+
+		o.ResultSchema = s
+		return nil
+	}
+}
+
+// ValidateResult is an ActOption to validate the result by the `validate` tags of the result type
+func ValidateResult() ActOption {
+	return func(o *ActOptions) error {
+		o.ValidateResult = true
+		return nil
+	}
+}
+
+// resultSchemas caches the reflected schemas by result type
+var resultSchemas sync.Map
+
+func reflectResultSchema(t reflect.Type) (*schema.Schema, error) {
+	if s, ok := resultSchemas.Load(t); ok {
+		return s.(*schema.Schema), nil
+	}
+
+	s, err := schema.Reflect(t)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resultSchemas.Store(t, s)
+
+	return s, nil
+}
+
+// decodeResult validates the result and decodes it into out. Violations are returned as
+// ValidationError with the path of every offending field.
+func decodeResult(result interface{}, out interface{}, opts ActOptions) error {
+	s := opts.ResultSchema
+
+	if s == nil && opts.ValidateResult {
+		var err error
+
+		if s, err = reflectResultSchema(reflect.TypeOf(out)); err != nil {
+			return NewErrorSimple("act: " + err.Error())
+		}
+	}
+
+	if s != nil {
+		if errs := s.Validate(result); len(errs) > 0 {
+			details := make([]ErrorDetail, len(errs))
+
+			for i, e := range errs {
+				details[i] = ErrorDetail(e)
+			}
+
+			return newResultError(details)
+		}
+	}
+
+	if !opts.StrictResult {
+		if err := mapstructure.Decode(result, out); err != nil {
+			return NewErrorSimple("act: " + err.Error())
+		}
+
+		return nil
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:  rejectLossyNumbers,
+		ErrorUnused: true,
+		Result:      out,
+	})
+
+	if err != nil {
+		return NewErrorSimple("act: " + err.Error())
+	}
+
+	if err := decoder.Decode(result); err != nil {
+		return newResultError(decodeErrorDetails(err))
+	}
+
+	return nil
+}
+
+func newResultError(details []ErrorDetail) *Error {
+	e := NewError(ValidationErrorName, "act: invalid result", ValidationErrorCode)
+	e.Details = details
+	return e
+}
+
+// rejectLossyNumbers is a decode hook which fails when a fraction or a negative number would be truncated
+func rejectLossyNumbers(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	f, ok := data.(float64)
+
+	if !ok {
+		return data, nil
+	}
+
+	switch to.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f != math.Trunc(f) {
+			return nil, fmt.Errorf("fraction %v can't be decoded into %s", f, to)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if f != math.Trunc(f) || f < 0 {
+			return nil, fmt.Errorf("%v can't be decoded into %s", f, to)
+		}
+	}
+
+	return data, nil
+}
+
+var (
+	decodeFieldError = regexp.MustCompile(`^(?:error decoding )?'([^']*)':? (.*)$`)
+	unusedKeysError  = regexp.MustCompile(`^'([^']*)' has invalid keys: (.*)$`)
+)
+
+// decodeErrorDetails converts the messages of a mapstructure error into details with the path of the field
+func decodeErrorDetails(err error) []ErrorDetail {
+	messages := []string{err.Error()}
+
+	if me, ok := err.(*mapstructure.Error); ok {
+		messages = me.Errors
+	}
+
+	details := []ErrorDetail{}
+
+	for _, msg := range messages {
+		if m := unusedKeysError.FindStringSubmatch(msg); m != nil {
+			for _, key := range strings.Split(m[2], ", ") {
+				field := key
+
+				if m[1] != "" {
+					field = m[1] + "." + key
+				}
+
+				details = append(details, ErrorDetail{Field: field, Rule: "unknown", Message: "is not a field of the result"})
+			}
+			continue
+		}
+
+		if m := decodeFieldError.FindStringSubmatch(msg); m != nil {
+			details = append(details, ErrorDetail{Field: m[1], Rule: "type", Message: m[2]})
+			continue
+		}
+
+		details = append(details, ErrorDetail{Rule: "type", Message: msg})
+	}
+
+	return details
+}
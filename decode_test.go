@@ -0,0 +1,72 @@
+package hemera
+
+import (
+	"testing"
+
+	"github.com/hemerajs/go-hemera/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+type StrictResponse struct {
+	Result int
+	Owner  struct {
+		Name string `validate:"required"`
+	} `validate:"required"`
+}
+
+func TestStrictResult(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h := newTestHemera(t, mt)
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply) {
+		reply.Send(map[string]interface{}{"result": 1.5, "owner": map[string]interface{}{"name": "a", "age": 1}})
+	})
+
+	res := &StrictResponse{}
+	ctx := h.Act(MathPattern{Topic: "math", Cmd: "add"}, res)
+	assert.Nil(ctx.Error, "Should decode loosely by default")
+	assert.Equal(res.Result, 1, "Should truncate the fraction")
+
+	ctx = h.Act(MathPattern{Topic: "math", Cmd: "add"}, &StrictResponse{}, StrictResult())
+	assert.True(IsValidationError(ctx.Error), "Should reply with a ValidationError")
+
+	details := map[string]string{}
+
+	for _, d := range ctx.Error.(*Error).Details {
+		details[d.Field] = d.Rule
+	}
+
+	assert.Equal(details, map[string]string{"Result": "type", "Owner.age": "unknown"}, "Should report the path of every field")
+
+	h2 := newTestHemera(t, mt, StrictDecoding(true))
+	ctx = h2.Act(MathPattern{Topic: "math", Cmd: "add"}, &StrictResponse{})
+	assert.True(IsValidationError(ctx.Error), "Should decode strictly by the option")
+
+	ctx = h.Act(MathPattern{Topic: "math", Cmd: "add"}, res, "invalid")
+	assert.Equal(ctx.Error.Error(), "act: invalid argument", "Should reject unknown arguments")
+}
+
+func TestValidateResult(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h := newTestHemera(t, mt)
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply) {
+		reply.Send(map[string]interface{}{"result": 3})
+	})
+
+	ctx := h.Act(MathPattern{Topic: "math", Cmd: "add"}, &StrictResponse{}, ValidateResult(), &Context{})
+	assert.Equal(ctx.Error.(*Error).Details, []ErrorDetail{
+		{Field: "Owner", Rule: "required", Message: "is required"},
+	}, "Should validate the result by tags")
+
+	ctx = h.Act(MathPattern{Topic: "math", Cmd: "add"}, &StrictResponse{}, ResultSchema(schema.MustParse([]byte(`{"properties": {"result": {"maximum": 2}}}`))))
+	assert.Equal(ctx.Error.(*Error).Details[0].Rule, "maximum", "Should validate the result by the schema")
+}
@@ -0,0 +1,508 @@
+package hemera
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// SecurityErrorName is the name of the error replied when a packet is not encrypted or signed as required
+	SecurityErrorName = "SecurityError"
+	// SecurityErrorCode is the code of the error replied when a packet is not encrypted or signed as required
+	SecurityErrorCode = 400
+	// DefaultSignatureMaxAge is the age after which signed packets are rejected as stale
+	DefaultSignatureMaxAge = time.Minute
+)
+
+var (
+	// envelopes are recognized by their first field, plain packets start with the pattern
+	sealedPrefix = []byte(`{"sealed":`)
+	packetPrefix = []byte(`{"packet":`)
+
+	errKeyringRequired = errors.New("keyring is required")
+	errUnknownKey      = errors.New("unknown encryption key")
+	errDecrypt         = errors.New("packet could not be decrypted")
+	errNotEncrypted    = errors.New("packet is not encrypted")
+	errUnknownSigner   = errors.New("unknown signing key")
+	errSignature       = errors.New("invalid signature")
+	errNotSigned       = errors.New("packet is not signed")
+	errWrongSubject    = errors.New("packet was signed for another subject")
+	errStale           = errors.New("packet is stale")
+	errDuplicate       = errors.New("duplicate packet")
+)
+
+type (
+	// Keyring holds the AES keys of encrypted packets by id. Packets are sealed with the current
+	// key, former keys stay in the keyring to open packets which were sealed before a rotation.
+	Keyring struct {
+		mu      sync.RWMutex
+		current string
+		keys    map[string]cipher.AEAD
+	}
+	// Signer signs outgoing packets, receivers verify the signature with the public key of the id
+	Signer struct {
+		KeyID string
+		Key   ed25519.PrivateKey
+	}
+	// envelope carries either the sealed or the plain packet and the detached signature of it
+	envelope struct {
+		Sealed    jsoniter.RawMessage `json:"sealed,omitempty"`
+		Packet    jsoniter.RawMessage `json:"packet,omitempty"`
+		Signature *signature          `json:"signature,omitempty"`
+	}
+	sealedPacket struct {
+		KeyID      string `json:"kid"`
+		Nonce      []byte `json:"nonce"`
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	// signature binds the body to the subject, the request id and the time of issue
+	signature struct {
+		KeyID     string `json:"kid"`
+		Subject   string `json:"sub"`
+		RequestID string `json:"rid"`
+		IssuedAt  int64  `json:"iat"`
+		Value     []byte `json:"sig"`
+	}
+	// replayCache remembers the signed packets of the max age to reject duplicates
+	replayCache struct {
+		mu     sync.Mutex
+		seen   map[string]time.Time
+		pruned time.Time
+	}
+)
+
+// NewKeyring create a keyring which seals with the key of the current id.
+// AES-128, AES-192 or AES-256 is chosen by the length of a key.
+func NewKeyring(current string, keys map[string][]byte) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD, len(keys))}
+
+	for id, key := range keys {
+		if err := k.add(id, key); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := k.keys[current]; !ok {
+		return nil, NewErrorSimple("keyring: current key " + current + " is missing")
+	}
+
+	k.current = current
+
+	return k, nil
+}
+
+// Rotate adds the key and seals all following packets with it
+func (k *Keyring) Rotate(id string, key []byte) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.add(id, key); err != nil {
+		return err
+	}
+
+	k.current = id
+
+	return nil
+}
+
+// Remove drops a former key, packets sealed with it can't be opened anymore
+func (k *Keyring) Remove(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if id == k.current {
+		return NewErrorSimple("keyring: current key can't be removed")
+	}
+
+	delete(k.keys, id)
+
+	return nil
+}
+
+// Current returns the id of the key which seals packets
+func (k *Keyring) Current() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.current
+}
+
+func (k *Keyring) add(id string, key []byte) error {
+	if id == "" {
+		return NewErrorSimple("keyring: key id is required")
+	}
+
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return NewErrorSimple("keyring: " + err.Error())
+	}
+
+	aead, err := cipher.NewGCM(block)
+
+	if err != nil {
+		return NewErrorSimple("keyring: " + err.Error())
+	}
+
+	k.keys[id] = aead
+
+	return nil
+}
+
+// seal encrypts the data with the current key, the key id is authenticated as additional data
+func (k *Keyring) seal(data []byte) (*sealedPacket, error) {
+	k.mu.RLock()
+	id, aead := k.current, k.keys[k.current]
+	k.mu.RUnlock()
+
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return &sealedPacket{KeyID: id, Nonce: nonce, Ciphertext: aead.Seal(nil, nonce, data, []byte(id))}, nil
+}
+
+func (k *Keyring) open(s *sealedPacket) ([]byte, error) {
+	k.mu.RLock()
+	aead, ok := k.keys[s.KeyID]
+	k.mu.RUnlock()
+
+	if !ok {
+		return nil, errUnknownKey
+	}
+
+	if len(s.Nonce) != aead.NonceSize() {
+		return nil, errDecrypt
+	}
+
+	data, err := aead.Open(nil, s.Nonce, s.Ciphertext, []byte(s.KeyID))
+
+	if err != nil {
+		return nil, errDecrypt
+	}
+
+	return data, nil
+}
+
+// Encryption is an Option to decrypt sealed packets with the keyring and to encrypt
+// the packets of Encrypted patterns and EncryptRequest acts
+func Encryption(k *Keyring) Option {
+	return func(o *Options) error {
+		if k == nil {
+			return NewErrorSimple("keyring is required")
+		}
+
+		o.Keyring = k
+		return nil
+	}
+}
+
+// EncryptPackets is an Option to encrypt every request, publish and response and to reject plain packets
+func EncryptPackets(enabled bool) Option {
+	return func(o *Options) error {
+		o.EncryptPackets = enabled
+		return nil
+	}
+}
+
+// SignPackets is an Option to attach a detached signature to every request, publish and response
+func SignPackets(s *Signer) Option {
+	return func(o *Options) error {
+		if s == nil || len(s.Key) != ed25519.PrivateKeySize {
+			return NewErrorSimple("signing key is required")
+		}
+
+		o.Signer = s
+		return nil
+	}
+}
+
+// SigningKeys is an Option to verify signatures with the public keys by id.
+// Packets with an unknown key id or an invalid signature are rejected.
+func SigningKeys(keys map[string]ed25519.PublicKey) Option {
+	return func(o *Options) error {
+		o.SigningKeys = keys
+		return nil
+	}
+}
+
+// SignatureMaxAge is an Option to reject signed packets which were issued before the age.
+// Duplicates of signed packets are rejected within the age.
+func SignatureMaxAge(d time.Duration) Option {
+	return func(o *Options) error {
+		if d <= 0 {
+			return NewErrorSimple("signature max age must be positive")
+		}
+
+		o.SignatureMaxAge = d
+		return nil
+	}
+}
+
+// RequireSignatures is an Option to reject every request and response without a valid signature
+func RequireSignatures(required bool) Option {
+	return func(o *Options) error {
+		o.RequireSignatures = required
+		return nil
+	}
+}
+
+// Encrypted is an AddOption to reject plain requests with a SecurityError, responses are encrypted
+func Encrypted() AddOption {
+	return func(o *AddOptions) error {
+		o.Encrypted = true
+		return nil
+	}
+}
+
+// Signed is an AddOption to reject requests without a valid signature with a SecurityError
+func Signed() AddOption {
+	return func(o *AddOptions) error {
+		o.Signed = true
+		return nil
+	}
+}
+
+// EncryptRequest is an ActOption to encrypt the request, the response must be encrypted as well
+func EncryptRequest() ActOption {
+	return func(o *ActOptions) error {
+		o.Encrypt = true
+		return nil
+	}
+}
+
+// NewSecurityError create the error which is replied when a packet is not encrypted or signed as required
+func NewSecurityError(message string) *Error {
+	return NewError(SecurityErrorName, message, SecurityErrorCode)
+}
+
+// IsSecurityError returns true when err was caused by a packet which is not encrypted or signed as required
+func IsSecurityError(err error) bool {
+	he, ok := err.(*Error)
+	return ok && he.Name == SecurityErrorName
+}
+
+// encodePacket marshals the packet of the subject and wraps it into an envelope when it's encrypted
+// or signed, a missing keyring is returned as SecurityError with the prefix
+func (h *Hemera) encodePacket(subject string, pack *packet, encrypt bool, prefix string) ([]byte, error) {
+	data, err := jsoniter.Marshal(pack)
+
+	if err != nil {
+		return nil, err
+	}
+
+	encrypt = encrypt || h.Opts.EncryptPackets
+
+	if !encrypt && h.Opts.Signer == nil {
+		return data, nil
+	}
+
+	env := envelope{Packet: data}
+
+	if encrypt {
+		if h.Opts.Keyring == nil {
+			return nil, NewSecurityError(prefix + errKeyringRequired.Error())
+		}
+
+		s, err := h.Opts.Keyring.seal(data)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if env.Sealed, err = jsoniter.Marshal(s); err != nil {
+			return nil, err
+		}
+
+		env.Packet = nil
+	}
+
+	if s := h.Opts.Signer; s != nil {
+		body := env.Packet
+
+		if encrypt {
+			body = env.Sealed
+		}
+
+		sig := &signature{KeyID: s.KeyID, Subject: subject, RequestID: pack.Request.ID, IssuedAt: time.Now().Unix()}
+		sig.Value = ed25519.Sign(s.Key, sig.message(body))
+		env.Signature = sig
+	}
+
+	return jsoniter.Marshal(&env)
+}
+
+// decodePacket verifies and decrypts the envelope of a message and unmarshals the packet, violations
+// of the encryption and signature options are returned as SecurityError with the prefix
+func (h *Hemera) decodePacket(m *Msg, pack *packet, prefix string) error {
+	data := m.Data
+
+	if !bytes.HasPrefix(data, sealedPrefix) && !bytes.HasPrefix(data, packetPrefix) {
+		if h.Opts.EncryptPackets {
+			return NewSecurityError(prefix + errNotEncrypted.Error())
+		}
+
+		if h.Opts.RequireSignatures {
+			return NewSecurityError(prefix + errNotSigned.Error())
+		}
+
+		return jsoniter.Unmarshal(data, pack)
+	}
+
+	env := envelope{}
+
+	if err := jsoniter.Unmarshal(data, &env); err != nil {
+		return err
+	}
+
+	data, err := h.openEnvelope(&env, m.Subject, pack)
+
+	if err != nil {
+		return NewSecurityError(prefix + err.Error())
+	}
+
+	if err := jsoniter.Unmarshal(data, pack); err != nil {
+		return err
+	}
+
+	// the request id is signed outside of the body to detect duplicates without decrypting
+	if pack.signed && pack.Request.ID != env.Signature.RequestID {
+		return NewSecurityError(prefix + errSignature.Error())
+	}
+
+	return nil
+}
+
+// openEnvelope returns the plain packet of the envelope and marks the packet as encrypted and signed
+func (h *Hemera) openEnvelope(env *envelope, subject string, pack *packet) ([]byte, error) {
+	body := env.Packet
+
+	if env.Sealed != nil {
+		body = env.Sealed
+	}
+
+	// without keys signatures can't be verified and are ignored
+	if env.Signature != nil && h.Opts.SigningKeys != nil {
+		key, ok := h.Opts.SigningKeys[env.Signature.KeyID]
+
+		if !ok {
+			return nil, errUnknownSigner
+		}
+
+		if !ed25519.Verify(key, env.Signature.message(body), env.Signature.Value) {
+			return nil, errSignature
+		}
+
+		if err := h.checkSignature(env.Signature, subject); err != nil {
+			return nil, err
+		}
+
+		pack.signed = true
+	}
+
+	if h.Opts.RequireSignatures && !pack.signed {
+		return nil, errNotSigned
+	}
+
+	if env.Sealed == nil {
+		if h.Opts.EncryptPackets {
+			return nil, errNotEncrypted
+		}
+
+		return env.Packet, nil
+	}
+
+	if h.Opts.Keyring == nil {
+		return nil, errKeyringRequired
+	}
+
+	s := sealedPacket{}
+
+	if err := jsoniter.Unmarshal(env.Sealed, &s); err != nil {
+		return nil, errDecrypt
+	}
+
+	pack.sealed = true
+
+	return h.Opts.Keyring.open(&s)
+}
+
+// checkSignature rejects a verified signature of another subject, a stale packet and a duplicate
+func (h *Hemera) checkSignature(sig *signature, subject string) error {
+	if sig.Subject != subject {
+		return errWrongSubject
+	}
+
+	maxAge := h.Opts.SignatureMaxAge
+
+	if maxAge <= 0 {
+		maxAge = DefaultSignatureMaxAge
+	}
+
+	now := time.Now()
+	issued := time.Unix(sig.IssuedAt, 0)
+
+	// the clocks of sender and receiver may differ in both directions
+	if now.Sub(issued) > maxAge || issued.Sub(now) > maxAge {
+		return errStale
+	}
+
+	if h.replays != nil && !h.replays.add(sig.KeyID+"\x00"+sig.RequestID, issued, now, maxAge) {
+		return errDuplicate
+	}
+
+	return nil
+}
+
+// message returns the signed data, the header fields are separated by zero bytes
+func (s *signature) message(body []byte) []byte {
+	msg := make([]byte, 0, len(s.Subject)+len(s.RequestID)+len(body)+24)
+	msg = append(msg, s.Subject...)
+	msg = append(msg, 0)
+	msg = append(msg, s.RequestID...)
+	msg = append(msg, 0)
+	msg = strconv.AppendInt(msg, s.IssuedAt, 10)
+	msg = append(msg, 0)
+
+	return append(msg, body...)
+}
+
+func newReplayCache() *replayCache {
+	return &replayCache{seen: make(map[string]time.Time)}
+}
+
+// add returns false when the key was seen, keys which are older than the max age are dropped
+// because their packets are rejected as stale
+func (c *replayCache) add(key string, issued, now time.Time, maxAge time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.pruned) > maxAge {
+		for k, t := range c.seen {
+			if now.Sub(t) > maxAge {
+				delete(c.seen, k)
+			}
+		}
+
+		c.pruned = now
+	}
+
+	if _, ok := c.seen[key]; ok {
+		return false
+	}
+
+	c.seen[key] = issued
+
+	return true
+}
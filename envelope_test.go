@@ -0,0 +1,184 @@
+package hemera
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryption(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	k1 := []byte("0123456789abcdef0123456789abcdef")
+	k2 := []byte("fedcba9876543210fedcba9876543210")
+
+	keyring, err := NewKeyring("k1", map[string][]byte{"k1": k1, "k2": k2})
+	assert.Nil(err, "Should create the keyring")
+
+	h := newTestHemera(t, mt, Encryption(keyring))
+
+	_, err = h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply) {
+		reply.Send(Response{Result: req.A + req.B})
+	}, Encrypted())
+	assert.Nil(err, "Should add the pattern")
+
+	// another tenant of the broker
+	wire := make(chan []byte, 4)
+	mt.QueueSubscribe("math", "", func(m *Msg) { wire <- m.Data })
+
+	res := &Response{}
+	ctx := h.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, res, EncryptRequest())
+	assert.Nil(ctx.Error, "Should act encrypted")
+	assert.Equal(res.Result, 3, "Should be 3")
+
+	select {
+	case data := <-wire:
+		assert.NotContains(string(data), `"cmd"`, "Should not leak the pattern")
+		assert.Contains(string(data), `"kid":"k1"`, "Should carry the key id")
+	case <-time.After(time.Second):
+		t.Fatal("Should receive the request")
+	}
+
+	client := newTestHemera(t, mt)
+	ctx = client.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, res)
+	assert.True(IsSecurityError(ctx.Error), "Should reject a plain request")
+	assert.Equal(ctx.Error.Error(), "add: packet is not encrypted", "Should reject a plain request")
+
+	rotated, err := NewKeyring("k1", map[string][]byte{"k1": k1})
+	assert.Nil(err, "Should create the keyring")
+	assert.Nil(rotated.Rotate("k2", k2), "Should rotate the key")
+	assert.Equal(rotated.Current(), "k2", "Should seal with the new key")
+
+	client = newTestHemera(t, mt, Encryption(rotated), EncryptPackets(true))
+	ctx = client.Act(RequestPattern{Topic: "math", Cmd: "add", A: 2, B: 2}, res)
+	assert.Nil(ctx.Error, "Should open packets of a former and the current key")
+	assert.Equal(res.Result, 4, "Should be 4")
+
+	unknown, err := NewKeyring("k3", map[string][]byte{"k3": k1})
+	assert.Nil(err, "Should create the keyring")
+	client = newTestHemera(t, mt, Encryption(unknown))
+	ctx = client.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, res, EncryptRequest())
+	assert.True(IsSecurityError(ctx.Error), "Should reject the plain error of an unknown key")
+	assert.Equal(ctx.Error.Error(), "act: response is not encrypted", "Should require a sealed error")
+
+	assert.Nil(rotated.Remove("k1"), "Should remove a former key")
+	assert.NotNil(rotated.Remove("k2"), "Should keep the current key")
+
+	_, err = CreateHemeraWithTransport(mt, EncryptPackets(true))
+	assert.Equal(err.Error(), "keyring is required", "Should require a keyring")
+
+	_, err = client.Add(MathPattern{Topic: "math", Cmd: "sub"}, func(req *RequestPattern, reply Reply) {}, Signed())
+	assert.Equal(err.Error(), "add: signing keys are required", "Should require signing keys")
+}
+
+func TestSignatures(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	_, forged, _ := ed25519.GenerateKey(nil)
+
+	h := newTestHemera(t, mt, SigningKeys(map[string]ed25519.PublicKey{"s1": pub}), SignPackets(&Signer{KeyID: "s1", Key: priv}))
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply) {
+		reply.Send(Response{Result: req.A + req.B})
+	}, Signed())
+
+	client := newTestHemera(t, mt, SignPackets(&Signer{KeyID: "s1", Key: priv}), SigningKeys(map[string]ed25519.PublicKey{"s1": pub}), RequireSignatures(true))
+
+	res := &Response{}
+	ctx := client.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, res)
+	assert.Nil(ctx.Error, "Should verify request and response")
+	assert.Equal(res.Result, 3, "Should be 3")
+
+	client = newTestHemera(t, mt)
+	ctx = client.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, res)
+	assert.Equal(ctx.Error.Error(), "add: packet is not signed", "Should reject an unsigned request")
+
+	client = newTestHemera(t, mt, SignPackets(&Signer{KeyID: "s2", Key: priv}))
+	ctx = client.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, res)
+	assert.Equal(ctx.Error.Error(), "add: unknown signing key", "Should reject an unknown signer")
+
+	client = newTestHemera(t, mt, SignPackets(&Signer{KeyID: "s1", Key: forged}))
+	ctx = client.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, res)
+	assert.True(IsSecurityError(ctx.Error), "Should reject a forged signature")
+	assert.Equal(ctx.Error.Error(), "add: invalid signature", "Should reject a forged signature")
+
+	server := newTestHemera(t, mt, SigningKeys(map[string]ed25519.PublicKey{"s1": pub}))
+	server.Add(MathPattern{Topic: "calc", Cmd: "sub"}, func(req *RequestPattern, reply Reply) {
+		reply.Send(Response{Result: req.A - req.B})
+	})
+
+	client = newTestHemera(t, mt, SigningKeys(map[string]ed25519.PublicKey{"s1": pub}), RequireSignatures(true))
+	ctx = client.Act(RequestPattern{Topic: "calc", Cmd: "sub", A: 3, B: 2}, res)
+	assert.Equal(ctx.Error.Error(), "act: packet is not signed", "Should reject an unsigned response")
+}
+
+func TestSignatureReplay(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	assert.Nil(err, "Should generate the key")
+
+	keys := SigningKeys(map[string]ed25519.PublicKey{"s1": pub})
+	signer := SignPackets(&Signer{KeyID: "s1", Key: priv})
+	h := newTestHemera(t, mt, keys, RequireSignatures(true))
+
+	for _, topic := range []string{"math", "admin"} {
+		h.Add(MathPattern{Topic: topic, Cmd: "add"}, func(req *RequestPattern, reply Reply) {
+			reply.Send(Response{Result: req.A + req.B})
+		}, Signed())
+	}
+
+	// another tenant of the broker
+	wire := make(chan []byte, 4)
+	mt.QueueSubscribe("math", "", func(m *Msg) { wire <- m.Data })
+
+	client := newTestHemera(t, mt, signer)
+	ctx := client.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, &Response{})
+	assert.Nil(ctx.Error, "Should have no error")
+
+	var data []byte
+
+	select {
+	case data = <-wire:
+	case <-time.After(time.Second):
+		t.Fatal("Should receive the request")
+	}
+
+	res := struct {
+		Error *Error `json:"error"`
+	}{}
+
+	m, err := mt.Request("math", data, time.Second)
+	assert.Nil(err, "Should reply")
+	jsoniter.Unmarshal(m.Data, &res)
+	assert.Equal(res.Error.Error(), "add: duplicate packet", "Should reject a duplicate")
+
+	m, err = mt.Request("admin", data, time.Second)
+	assert.Nil(err, "Should reply")
+	jsoniter.Unmarshal(m.Data, &res)
+	assert.Equal(res.Error.Error(), "add: packet was signed for another subject", "Should reject a redirected packet")
+
+	strict := newTestHemera(t, mt, keys, SignatureMaxAge(time.Nanosecond))
+	strict.Add(MathPattern{Topic: "calc", Cmd: "add"}, func(req *RequestPattern, reply Reply) {
+		reply.Send(Response{Result: req.A + req.B})
+	}, Signed())
+
+	ctx = client.Act(RequestPattern{Topic: "calc", Cmd: "add", A: 1, B: 2}, &Response{})
+	assert.Equal(ctx.Error.Error(), "add: packet is stale", "Should reject a stale packet")
+
+	_, err = CreateHemeraWithTransport(mt, SignatureMaxAge(0))
+	assert.Equal(err.Error(), "signature max age must be positive", "Should require a positive age")
+}
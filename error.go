@@ -1,10 +1,33 @@
 package hemera
 
+const (
+	// RateLimitErrorName is the name of the error replied when a pattern is throttled
+	RateLimitErrorName = "RateLimitError"
+	// RateLimitErrorCode is the code of the error replied when a pattern is throttled
+	RateLimitErrorCode = 429
+	// PatternNotFoundErrorName is the name of the error replied when no pattern and no fallback matched
+	PatternNotFoundErrorName = "PatternNotFoundError"
+	// PatternNotFoundErrorCode is the code of the error replied when no pattern and no fallback matched
+	PatternNotFoundErrorCode = 404
+	// ValidationErrorName is the name of the error replied when a request violates the schema of the pattern
+	ValidationErrorName = "ValidationError"
+	// ValidationErrorCode is the code of the error replied when a request violates the schema of the pattern
+	ValidationErrorCode = 400
+)
+
 type (
 	Error struct {
 		Name    string `json:"name"`
 		Message string `json:"message"`
 		Code    int16  `json:"code"`
+		// Details are the violated rules of a ValidationError
+		Details []ErrorDetail `json:"details,omitempty"`
+	}
+	// ErrorDetail is a violated rule of a field
+	ErrorDetail struct {
+		Field   string `json:"field"`
+		Rule    string `json:"rule"`
+		Message string `json:"message"`
 	}
 )
 
@@ -22,6 +45,30 @@ func NewErrorSimple(message string) *Error {
 	}
 }
 
+// NewRateLimitError create the error which is replied when a request exceeds the rate limit
+func NewRateLimitError(message string) *Error {
+	return NewError(RateLimitErrorName, message, RateLimitErrorCode)
+}
+
+// IsRateLimitError returns true when err was caused by a rate limit
+func IsRateLimitError(err error) bool {
+	he, ok := err.(*Error)
+	return ok && he.Name == RateLimitErrorName
+}
+
+// NewValidationError create the error which is replied when a request violates the schema of the pattern
+func NewValidationError(details []ErrorDetail) *Error {
+	e := NewError(ValidationErrorName, "add: invalid request", ValidationErrorCode)
+	e.Details = details
+	return e
+}
+
+// IsValidationError returns true when err was caused by an invalid request
+func IsValidationError(err error) bool {
+	he, ok := err.(*Error)
+	return ok && he.Name == ValidationErrorName
+}
+
 func (e *Error) Error() string {
 	return e.Message
 }
@@ -0,0 +1,362 @@
+// Package gateway translates HTTP requests into acts.
+package gateway
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/hemerajs/go-hemera"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// MetaHeader carries the meta of a request and a response as JSON object
+	MetaHeader = "X-Hemera-Meta"
+	// DelegateHeader carries the delegate of a request as JSON object
+	DelegateHeader = "X-Hemera-Delegate"
+	// DefaultMaxBodySize is the default limit of a request body in bytes
+	DefaultMaxBodySize = 1 << 20
+)
+
+var (
+	errRouteNotFound    = hemera.NewError(hemera.PatternNotFoundErrorName, "gateway: route could not be found", http.StatusNotFound)
+	errMethodNotAllowed = hemera.NewError("MethodNotAllowedError", "gateway: method not allowed", http.StatusMethodNotAllowed)
+	errBodyTooLarge     = hemera.NewErrorSimple("gateway: request body too large")
+)
+
+type (
+	// Option is a function on the options of the gateway
+	Option  func(*Options) error
+	Options struct {
+		Routes []Route
+		// ActPath is the path of the generic endpoint which accepts a JSON pattern, empty disables it
+		ActPath     string
+		MaxBodySize int64
+	}
+	// Route maps an HTTP method and path onto a pattern. Path parameters like
+	// `/users/{id}`, query parameters and the fields of a JSON body are added to the pattern.
+	Route struct {
+		Method  string                 `json:"method"`
+		Path    string                 `json:"path"`
+		Pattern map[string]interface{} `json:"pattern"`
+	}
+	// Gateway is an http.Handler which acts on the patterns of the routes
+	Gateway struct {
+		Hemera *hemera.Hemera
+		Opts   Options
+	}
+)
+
+// WithRoute is an Option to map an HTTP method and path onto a pattern.
+// The keys of the pattern are lowercased like the router compares them.
+func WithRoute(method, path string, pattern map[string]interface{}) Option {
+	return func(o *Options) error {
+		fixed := make(map[string]interface{}, len(pattern))
+
+		if err := addFields(fixed, pattern); err != nil {
+			return err
+		}
+
+		if _, ok := fixed["topic"]; !ok {
+			return hemera.NewErrorSimple("gateway: topic is required")
+		}
+
+		o.Routes = append(o.Routes, Route{Method: method, Path: path, Pattern: fixed})
+		return nil
+	}
+}
+
+// Routes is an Option to add a route table e.g. from a configuration file
+func Routes(routes []Route) Option {
+	return func(o *Options) error {
+		for _, r := range routes {
+			if err := WithRoute(r.Method, r.Path, r.Pattern)(o); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// ActPath is an Option to set the path of the generic endpoint which accepts a JSON pattern
+func ActPath(path string) Option {
+	return func(o *Options) error {
+		o.ActPath = path
+		return nil
+	}
+}
+
+// MaxBodySize is an Option to limit the size of request bodies in bytes
+func MaxBodySize(n int64) Option {
+	return func(o *Options) error {
+		o.MaxBodySize = n
+		return nil
+	}
+}
+
+// New create a gateway which acts with h
+func New(h *hemera.Hemera, options ...Option) (*Gateway, error) {
+	opts := Options{MaxBodySize: DefaultMaxBodySize}
+
+	for _, opt := range options {
+		if err := opt(&opts); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Gateway{Hemera: h, Opts: opts}, nil
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var pattern map[string]interface{}
+
+	if g.Opts.ActPath != "" && r.URL.Path == g.Opts.ActPath {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, errMethodNotAllowed)
+			return
+		}
+
+		pattern = map[string]interface{}{}
+	} else {
+		route, params, status := g.match(r)
+
+		if route == nil && status == http.StatusMethodNotAllowed {
+			writeError(w, status, errMethodNotAllowed)
+			return
+		}
+
+		if route == nil {
+			writeError(w, status, errRouteNotFound)
+			return
+		}
+
+		// the fields of the route can't be overridden by the request, path
+		// parameters take precedence over query parameters and the body
+		pattern = make(map[string]interface{}, len(route.Pattern)+len(params))
+
+		for key, val := range route.Pattern {
+			pattern[key] = val
+		}
+
+		fields := make(map[string]interface{}, len(params))
+
+		for key, val := range params {
+			fields[key] = parseValue(val)
+		}
+
+		if err := addFields(pattern, fields); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		fields = map[string]interface{}{}
+
+		for key, val := range r.URL.Query() {
+			fields[key] = parseValue(val[len(val)-1])
+		}
+
+		if err := addFields(pattern, fields); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if err := g.decodeBody(r, pattern); err == errBodyTooLarge {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := &hemera.Context{}
+
+	if err := parseHeader(r.Header.Get(MetaHeader), &ctx.Meta); err != nil {
+		writeError(w, http.StatusBadRequest, hemera.NewErrorSimple("gateway: invalid meta header"))
+		return
+	}
+
+	if err := parseHeader(r.Header.Get(DelegateHeader), &ctx.Delegate); err != nil {
+		writeError(w, http.StatusBadRequest, hemera.NewErrorSimple("gateway: invalid delegate header"))
+		return
+	}
+
+	// the bearer token is verified by the services
+	if token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "); token != r.Header.Get("Authorization") {
+		ctx.SetDelegate(hemera.TokenDelegateKey, token)
+	}
+
+	var out interface{}
+	res := g.Hemera.Act(pattern, &out, ctx)
+
+	if len(res.Meta) > 0 {
+		if data, err := jsoniter.Marshal(res.Meta); err == nil {
+			w.Header().Set(MetaHeader, string(data))
+		}
+	}
+
+	if res.Error != nil {
+		writeError(w, StatusCode(res.Error), res.Error)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"result": out})
+}
+
+// match returns the route of the request and its path parameters, otherwise the status code
+func (g *Gateway) match(r *http.Request) (*Route, map[string]string, int) {
+	status := http.StatusNotFound
+	segments := splitPath(r.URL.Path)
+
+	for i := range g.Opts.Routes {
+		route := &g.Opts.Routes[i]
+		params, ok := matchPath(splitPath(route.Path), segments)
+
+		if !ok {
+			continue
+		}
+
+		if route.Method != "" && !strings.EqualFold(route.Method, r.Method) {
+			status = http.StatusMethodNotAllowed
+			continue
+		}
+
+		return route, params, http.StatusOK
+	}
+
+	return nil, nil, status
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func matchPath(route, segments []string) (map[string]string, bool) {
+	if len(route) != len(segments) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+
+	for i, s := range route {
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			params[s[1:len(s)-1]] = segments[i]
+		} else if s != segments[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// decodeBody adds the fields of a JSON object body to the pattern
+func (g *Gateway) decodeBody(r *http.Request, pattern map[string]interface{}) error {
+	if r.Body == nil || r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, g.Opts.MaxBodySize+1))
+
+	if err != nil {
+		return hemera.NewErrorSimple("gateway: " + err.Error())
+	}
+
+	if int64(len(data)) > g.Opts.MaxBodySize {
+		return errBodyTooLarge
+	}
+
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil
+	}
+
+	body := map[string]interface{}{}
+
+	if err := jsoniter.Unmarshal(data, &body); err != nil {
+		return hemera.NewErrorSimple("gateway: body must be a JSON object")
+	}
+
+	return addFields(pattern, body)
+}
+
+// addFields adds the fields to the pattern by their lowercased key, fields of the pattern are kept.
+// Keys which only differ in case are rejected, the router could not tell them apart.
+func addFields(pattern, fields map[string]interface{}) error {
+	lower := make(map[string]interface{}, len(fields))
+
+	for key, val := range fields {
+		l := strings.ToLower(key)
+
+		if _, ok := lower[l]; ok {
+			return hemera.NewErrorSimple("gateway: duplicate field " + l)
+		}
+
+		lower[l] = val
+	}
+
+	for key, val := range lower {
+		if _, ok := pattern[key]; !ok {
+			pattern[key] = val
+		}
+	}
+
+	return nil
+}
+
+// parseValue decodes numbers and booleans of path and query parameters
+func parseValue(s string) interface{} {
+	var v interface{}
+
+	if err := jsoniter.UnmarshalFromString(s, &v); err == nil {
+		switch v.(type) {
+		case float64, bool:
+			return v
+		}
+	}
+
+	return s
+}
+
+func parseHeader(value string, out interface{}) error {
+	if value == "" {
+		return nil
+	}
+
+	return jsoniter.UnmarshalFromString(value, out)
+}
+
+// StatusCode returns the HTTP status code of an act error. Codes of hemera errors
+// in the range of 400-599 are used as status code.
+func StatusCode(err error) int {
+	switch err {
+	case hemera.ErrRequestTimeout:
+		return http.StatusGatewayTimeout
+	case hemera.ErrTransportClosed:
+		return http.StatusServiceUnavailable
+	}
+
+	if he, ok := err.(*hemera.Error); ok && he.Code >= 400 && he.Code < 600 {
+		return int(he.Code)
+	}
+
+	return http.StatusInternalServerError
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	he, ok := err.(*hemera.Error)
+
+	if !ok {
+		he = hemera.NewErrorSimple(err.Error())
+	}
+
+	writeJSON(w, status, map[string]interface{}{"error": he})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, _ := jsoniter.Marshal(v)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
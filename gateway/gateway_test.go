@@ -0,0 +1,166 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hemerajs/go-hemera"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+type MathPattern struct {
+	Topic string
+	Cmd   string
+}
+
+type RequestPattern struct {
+	Topic string
+	Cmd   string
+	A     int
+	B     int
+}
+
+type Response struct {
+	Result int
+}
+
+func newGateway(t *testing.T, options ...Option) (*Gateway, *hemera.MemoryTransport) {
+	mt := hemera.NewMemoryTransport()
+	h, err := hemera.CreateHemeraWithTransport(mt)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply hemera.Reply, ctx *hemera.Context) {
+		reply.Send(Response{Result: req.A + req.B})
+	})
+
+	h.Add(MathPattern{Topic: "math", Cmd: "div"}, func(req *RequestPattern, reply hemera.Reply) {
+		reply.Send(hemera.NewError("DivisionError", "division by zero", 422))
+	})
+
+	h.Add(MathPattern{Topic: "math", Cmd: "whoami"}, func(req *RequestPattern, reply hemera.Reply, ctx *hemera.Context) {
+		reply.Send(ctx.GetDelegate(hemera.TokenDelegateKey))
+	})
+
+	g, err := New(&h, options...)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return g, mt
+}
+
+func serve(g *Gateway, method, target, body string, header http.Header) (*httptest.ResponseRecorder, map[string]interface{}) {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+
+	for key := range header {
+		r.Header.Set(key, header.Get(key))
+	}
+
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+
+	res := map[string]interface{}{}
+	jsoniter.Unmarshal(w.Body.Bytes(), &res)
+
+	return w, res
+}
+
+func TestRoutes(t *testing.T) {
+	assert := assert.New(t)
+
+	g, mt := newGateway(t,
+		WithRoute("GET", "/math/{cmd}", map[string]interface{}{"topic": "math"}),
+		WithRoute("POST", "/add", map[string]interface{}{"topic": "math", "cmd": "add"}),
+	)
+	defer mt.Close()
+
+	w, res := serve(g, "GET", "/math/add?a=1&b=2", "", nil)
+	assert.Equal(w.Code, 200, "Should be 200")
+	assert.Equal(res["result"], map[string]interface{}{"Result": 3.0}, "Should act with path and query parameters")
+
+	w, res = serve(g, "POST", "/add", `{"a": 2, "b": 2, "cmd": "div"}`, http.Header{MetaHeader: {`{"tenant": "a"}`}})
+	assert.Equal(w.Code, 200, "Should be 200")
+	assert.Equal(res["result"], map[string]interface{}{"Result": 4.0}, "Should not override the fields of the route")
+	assert.Equal(w.Header().Get(MetaHeader), `{"tenant":"a"}`, "Should return the meta")
+
+	w, res = serve(g, "GET", "/math/div", "", nil)
+	assert.Equal(w.Code, 422, "Should use the code of the error")
+	assert.Equal(res["error"].(map[string]interface{})["name"], "DivisionError", "Should be the error of the handler")
+
+	w, _ = serve(g, "GET", "/math/sub", "", nil)
+	assert.Equal(w.Code, 404, "Should be 404 when no pattern matched")
+
+	w, _ = serve(g, "GET", "/add", "", nil)
+	assert.Equal(w.Code, 405, "Should be 405")
+
+	w, _ = serve(g, "GET", "/unknown", "", nil)
+	assert.Equal(w.Code, 404, "Should be 404")
+
+	w, _ = serve(g, "POST", "/add", `[1]`, nil)
+	assert.Equal(w.Code, 400, "Should reject a body which is not an object")
+
+	w, res = serve(g, "GET", "/math/whoami", "", http.Header{"Authorization": {"Bearer abc"}})
+	assert.Equal(res["result"], "abc", "Should pass the bearer token in the delegate")
+
+	w, _ = serve(g, "POST", "/add", `{}`, http.Header{DelegateHeader: {`x`}})
+	assert.Equal(w.Code, 400, "Should reject an invalid delegate header")
+}
+
+func TestRouteFieldCase(t *testing.T) {
+	assert := assert.New(t)
+
+	g, mt := newGateway(t, WithRoute("POST", "/add", map[string]interface{}{"Topic": "math", "cmd": "add"}))
+	defer mt.Close()
+
+	// map iteration decided which key won, repeat to hit every order
+	for i := 0; i < 20; i++ {
+		w, res := serve(g, "POST", "/add?Cmd=whoami&TOPIC=auth&a=1", `{"CMD": "whoami", "Topic": "auth", "B": 2}`, nil)
+		assert.Equal(w.Code, 200, "Should be 200")
+		assert.Equal(res["result"], map[string]interface{}{"Result": 3.0}, "Should not override the fields of the route by case")
+	}
+
+	w, _ := serve(g, "POST", "/add?a=1&A=2", "", nil)
+	assert.Equal(w.Code, 400, "Should reject query keys which only differ in case")
+
+	w, _ = serve(g, "POST", "/add", `{"b": 1, "B": 2}`, nil)
+	assert.Equal(w.Code, 400, "Should reject body keys which only differ in case")
+}
+
+func TestActPath(t *testing.T) {
+	assert := assert.New(t)
+
+	g, mt := newGateway(t, ActPath("/act"), MaxBodySize(64))
+	defer mt.Close()
+
+	w, res := serve(g, "POST", "/act", `{"topic": "math", "cmd": "add", "a": 1, "b": 1}`, nil)
+	assert.Equal(w.Code, 200, "Should be 200")
+	assert.Equal(res["result"], map[string]interface{}{"Result": 2.0}, "Should act on the pattern of the body")
+
+	w, _ = serve(g, "GET", "/act", "", nil)
+	assert.Equal(w.Code, 405, "Should be 405")
+
+	w, _ = serve(g, "POST", "/act", `{"cmd": "add"}`, nil)
+	assert.Equal(w.Code, 500, "Should reject a pattern without topic")
+
+	w, _ = serve(g, "POST", "/act", `{"topic": "math", "cmd": "add", "note": "`+strings.Repeat("a", 64)+`"}`, nil)
+	assert.Equal(w.Code, 413, "Should reject large bodies")
+
+	mt.Close()
+	w, _ = serve(g, "POST", "/act", `{"topic": "math", "cmd": "add"}`, nil)
+	assert.Equal(w.Code, 503, "Should be 503 when the transport is closed")
+}
+
+func TestStatusCode(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(StatusCode(hemera.ErrRequestTimeout), 504, "Should be 504")
+	assert.Equal(StatusCode(hemera.NewRateLimitError("")), 429, "Should be 429")
+	assert.Equal(StatusCode(hemera.NewErrorSimple("")), 500, "Should be 500")
+}
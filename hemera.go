@@ -1,13 +1,16 @@
 package hemera
 
 import (
-	"log"
+	"crypto/ed25519"
+	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/fatih/structs"
 	"github.com/hemerajs/go-hemera/router"
-	jsoniter "github.com/json-iterator/go"
+	"github.com/hemerajs/go-hemera/schema"
 	"github.com/mitchellh/mapstructure"
 	nats "github.com/nats-io/go-nats"
 	"github.com/nats-io/nuid"
@@ -30,12 +33,63 @@ type (
 	Options struct {
 		Timeout          time.Duration
 		IndexingStrategy bool
+		ActRateLimiter   *RateLimiter
+		PubsubFanout     bool
+		StrictPatterns   bool
+		StrictDecoding   bool
+		TokenVerifier    TokenVerifier
+		TokenSource      func() (string, error)
+		// Keyring decrypts sealed packets and encrypts packets on demand
+		Keyring        *Keyring
+		EncryptPackets bool
+		Signer         *Signer
+		// SigningKeys are the public keys of trusted signers by key id
+		SigningKeys       map[string]ed25519.PublicKey
+		RequireSignatures bool
+		SignatureMaxAge   time.Duration
+	}
+	// AddOption is a function on the options of a single pattern
+	AddOption  func(*AddOptions) error
+	AddOptions struct {
+		RateLimiter  *RateLimiter
+		RateLimitKey string
+		Priority     int
+		Schema       *schema.Schema
+		// the schema is reflected from the `validate` tags of the request type
+		ValidateTags bool
+		Description  string
+		ResponseType reflect.Type
+		Errors       []*Error
+		// a valid token is required
+		Authenticated bool
+		Scopes        []string
+		Roles         []string
+		// requests must be encrypted or signed
+		Encrypted bool
+		Signed    bool
 	}
 	Handler interface{}
-	Hemera  struct {
-		Conn   *nats.Conn
-		Router *router.Router
-		Opts   Options
+	handler struct {
+		cb      Handler
+		argType reflect.Type
+		numArgs int
+		opts    AddOptions
+	}
+	Hemera struct {
+		ID        string
+		Conn      *nats.Conn
+		Transport Transport
+		Router    *router.Router
+		Opts      Options
+		listSub   Subscription
+		// answers contract requests
+		contractSub Subscription
+		// guards the lazy list and contract subscriptions
+		listMu *sync.Mutex
+		// fallback handlers by topic
+		fallbacks *router.Router
+		// signed packets which were received
+		replays *replayCache
 	}
 	request struct {
 		ID          string `json:"id"`
@@ -58,6 +112,11 @@ type (
 		Trace    Trace       `json:"trace"`
 		Request  request     `json:"request"`
 		Error    *Error      `json:"error"`
+		// fields with the `hemera:"payload"` tag are sent outside of the routed pattern
+		Payload map[string]interface{} `json:"payload,omitempty"`
+		// the packet was received encrypted or with a valid signature
+		sealed bool
+		signed bool
 	}
 	Meta     map[string]interface{}
 	Delegate map[string]interface{}
@@ -67,19 +126,30 @@ func GetDefaultOptions() Options {
 	opts := Options{
 		Timeout:          RequestTimeout,
 		IndexingStrategy: false,
+		SignatureMaxAge:  DefaultSignatureMaxAge,
 	}
 	return opts
 }
 
 // New create a new Hemera struct
 func CreateHemera(conn *nats.Conn, options ...Option) (Hemera, error) {
+	h, err := CreateHemeraWithTransport(NewNatsTransport(conn), options...)
+	h.Conn = conn
+	return h, err
+}
+
+// CreateHemeraWithTransport create a new Hemera struct which communicates over the transport
+func CreateHemeraWithTransport(t Transport, options ...Option) (Hemera, error) {
 	opts := GetDefaultOptions()
 	for _, opt := range options {
 		if err := opt(&opts); err != nil {
-			return Hemera{Opts: opts, Router: router.NewRouter(opts.IndexingStrategy)}, err
+			return Hemera{Opts: opts, Router: router.NewRouter(opts.IndexingStrategy), fallbacks: router.NewRouter(false)}, err
 		}
 	}
-	return Hemera{Conn: conn, Opts: opts, Router: router.NewRouter(opts.IndexingStrategy)}, nil
+	if opts.EncryptPackets && opts.Keyring == nil {
+		return Hemera{Opts: opts, Router: router.NewRouter(opts.IndexingStrategy), fallbacks: router.NewRouter(false)}, NewErrorSimple("keyring is required")
+	}
+	return Hemera{ID: nuid.Next(), Transport: t, Opts: opts, Router: router.NewRouter(opts.IndexingStrategy), fallbacks: router.NewRouter(false), listMu: &sync.Mutex{}, replays: newReplayCache()}, nil
 }
 
 // Timeout is an Option to set the timeout for a act request
@@ -97,8 +167,100 @@ func IndexingStrategy(isDeep bool) Option {
 	}
 }
 
+// StrictPatterns is an Option to reject patterns which shadow or are shadowed by a registered pattern
+func StrictPatterns(strict bool) Option {
+	return func(o *Options) error {
+		o.StrictPatterns = strict
+		return nil
+	}
+}
+
+// ActRateLimit is an Option to throttle outgoing act requests per topic
+func ActRateLimit(rate float64, burst int) Option {
+	return func(o *Options) error {
+		o.ActRateLimiter = NewRateLimiter(rate, burst)
+		return nil
+	}
+}
+
+// RateLimit is an AddOption to reject requests which exceed the limiter with a RateLimitError.
+// When metaKey is not empty every value of the meta field is limited independently.
+func RateLimit(l *RateLimiter, metaKey string) AddOption {
+	return func(o *AddOptions) error {
+		if l == nil {
+			return NewErrorSimple("add: rate limiter is required")
+		}
+
+		o.RateLimiter = l
+		o.RateLimitKey = metaKey
+		return nil
+	}
+}
+
+// Priority is an AddOption to win over all patterns with a lower priority regardless of the indexing strategy
+func Priority(n int) AddOption {
+	return func(o *AddOptions) error {
+		o.Priority = n
+		return nil
+	}
+}
+
+// Schema is an AddOption to reject requests which violate the schema with a ValidationError
+func Schema(s *schema.Schema) AddOption {
+	return func(o *AddOptions) error {
+		if s == nil {
+			return NewErrorSimple("add: schema is required")
+		}
+
+		o.Schema = s
+		return nil
+	}
+}
+
+// ValidateTags is an AddOption to validate requests by the `validate` tags of the request type e.g. `validate:"required,min=1"`
+func ValidateTags() AddOption {
+	return func(o *AddOptions) error {
+		o.ValidateTags = true
+		return nil
+	}
+}
+
+// newAddOptions applies the options of a handler with the request type argType
+func (h *Hemera) newAddOptions(argType reflect.Type, options []AddOption) (AddOptions, error) {
+	addOpts := AddOptions{}
+	for _, opt := range options {
+		if err := opt(&addOpts); err != nil {
+			return addOpts, err
+		}
+	}
+
+	if addOpts.ValidateTags && addOpts.Schema == nil {
+		s, err := schema.Reflect(argType)
+
+		if err != nil {
+			return addOpts, NewErrorSimple("add: " + err.Error())
+		}
+
+		addOpts.Schema = s
+	}
+
+	if addOpts.Authenticated && h.Opts.TokenVerifier == nil {
+		return addOpts, NewErrorSimple("add: token verifier is required")
+	}
+
+	if addOpts.Encrypted && h.Opts.Keyring == nil {
+		return addOpts, NewErrorSimple("add: keyring is required")
+	}
+
+	if addOpts.Signed && h.Opts.SigningKeys == nil {
+		return addOpts, NewErrorSimple("add: signing keys are required")
+	}
+
+	return addOpts, nil
+}
+
 // Add is a method to subscribe on a specific topic
-func (h *Hemera) Add(p interface{}, cb Handler) (*nats.Subscription, error) {
+func (h *Hemera) Add(p interface{}, cb Handler, options ...AddOption) (Subscription, error) {
 	s := structs.New(p)
 	f := s.Field("Topic")
 
@@ -119,19 +281,38 @@ func (h *Hemera) Add(p interface{}, cb Handler) (*nats.Subscription, error) {
 		return nil, NewErrorSimple("add: invalid add handler arguments")
 	}
 
-	lp := h.Router.Lookup(p)
+	addOpts, err := h.newAddOptions(argTypes[0], options)
+
+	if err != nil {
+		return nil, err
+	}
 
-	if lp != nil {
-		return nil, NewErrorSimple("add: duplicate pattern")
+	if err := h.subscribeList(); err != nil {
+		return nil, err
 	}
 
-	h.Router.Add(p, cb)
+	hd := &handler{cb: cb, argType: argTypes[0], numArgs: numArgs, opts: addOpts}
+
+	err = h.Router.AddChecked(p, hd, addOpts.Priority, func(conflicts []router.Conflict) error {
+		for _, c := range conflicts {
+			if c.Kind == router.ConflictDuplicate {
+				return NewErrorSimple("add: duplicate pattern")
+			}
+
+			if h.Opts.StrictPatterns && c.Kind != router.ConflictAmbiguous {
+				return NewErrorSimple("add: pattern conflict, " + c.String())
+			}
+		}
 
-	// Response struct
-	argMsgType := argTypes[0]
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
 
-	sub, err := h.Conn.QueueSubscribe(topic, topic, func(m *nats.Msg) {
-		h.callAddAction(topic, m, argMsgType, numArgs)
+	sub, err := h.Transport.QueueSubscribe(topic, topic, func(m *Msg) {
+		h.callAddAction(topic, m)
 	})
 
 	if err != nil {
@@ -141,73 +322,329 @@ func (h *Hemera) Add(p interface{}, cb Handler) (*nats.Subscription, error) {
 	return sub, nil
 }
 
-func (h *Hemera) callAddAction(topic string, m *nats.Msg, mContainer reflect.Type, numArgs int) {
-	var oPtr reflect.Value
+// Fallback is a method to handle all requests of a topic which don't match any pattern
+func (h *Hemera) Fallback(topic string, cb Handler, options ...AddOption) (Subscription, error) {
+	if topic == "" {
+		return nil, NewErrorSimple("add: topic is required")
+	}
 
-	if mContainer.Kind() != reflect.Ptr {
-		oPtr = reflect.New(mContainer)
-	} else {
-		oPtr = reflect.New(mContainer.Elem())
+	argTypes, numArgs := ArgInfo(cb)
+
+	if numArgs < 2 {
+		return nil, NewErrorSimple("add: invalid add handler arguments")
 	}
 
-	pack := packet{}
+	addOpts, err := h.newAddOptions(argTypes[0], options)
 
-	// decoding hemera packet
-	jsoniter.Unmarshal(m.Data, &pack)
+	if err != nil {
+		return nil, err
+	}
 
-	context := &Context{Trace: pack.Trace, Meta: pack.Meta, Delegate: pack.Delegate}
+	if err := h.subscribeList(); err != nil {
+		return nil, err
+	}
 
-	oContextPtr := reflect.ValueOf(context)
+	key := map[string]interface{}{"topic": topic}
 
-	// Pattern is the request
-	o := pack.Pattern
+	hd := &handler{cb: cb, argType: argTypes[0], numArgs: numArgs, opts: addOpts}
 
-	// return the value of oPtr as an interface{}
-	oi := oPtr.Interface()
+	err = h.fallbacks.AddChecked(key, hd, 0, func(conflicts []router.Conflict) error {
+		for _, c := range conflicts {
+			if c.Kind == router.ConflictDuplicate {
+				return NewErrorSimple("add: duplicate fallback")
+			}
+		}
 
-	// Decode map to struct
-	err := mapstructure.Decode(o, oi)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return h.Transport.QueueSubscribe(topic, topic, func(m *Msg) {
+		h.callAddAction(topic, m)
+	})
+}
+
+// Import adds the patterns of an exported routing table. The handler names are resolved to the
+// handlers which are registered on the instance, an imported pattern shares the options of its
+// handler. The subscriptions of topics which had no pattern before are returned.
+func (h *Hemera) Import(data []byte) ([]Subscription, error) {
+	t, err := router.DecodeTable(data)
 
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	handlers := map[string]*handler{}
+	topics := map[string]bool{}
+
+	for _, ps := range h.Router.List() {
+		if hd, ok := ps.Payload.(*handler); ok {
+			handlers[hd.Name()] = hd
+		}
+
+		if topic, ok := ps.Fields["topic"].(string); ok {
+			topics[topic] = true
+		}
+	}
+
+	type pending struct {
+		pattern map[string]interface{}
+		hd      *handler
+		e       router.TableEntry
+	}
+
+	patterns := []pending{}
+
+	// resolve all patterns before the first one is added
+	for i, e := range t.Patterns {
+		pattern, err := e.Pattern()
+
+		if err != nil {
+			return nil, NewErrorSimple(fmt.Sprintf("import: pattern %d %v", i, err))
+		}
+
+		if _, ok := pattern["topic"].(string); !ok {
+			return nil, NewErrorSimple(fmt.Sprintf("import: pattern %d has no topic", i))
+		}
+
+		hd, ok := handlers[e.Handler]
+
+		if !ok {
+			return nil, NewErrorSimple(fmt.Sprintf("import: pattern %d: handler %s is not registered", i, e.Handler))
+		}
+
+		patterns = append(patterns, pending{pattern: pattern, hd: hd, e: e})
 	}
 
-	e := oPtr.Elem().Interface()
+	subs := []Subscription{}
 
-	p := h.Router.Lookup(e)
+	for _, p := range patterns {
+		err := h.Router.AddChecked(p.pattern, p.hd, p.e.Priority, func(conflicts []router.Conflict) error {
+			for _, c := range conflicts {
+				if c.Kind == router.ConflictDuplicate {
+					return NewErrorSimple("import: pattern conflict, " + c.String())
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return subs, err
+		}
+
+		topic := p.pattern["topic"].(string)
+
+		if topics[topic] {
+			continue
+		}
+
+		topics[topic] = true
+		sub, err := h.Transport.QueueSubscribe(topic, topic, func(m *Msg) {
+			h.callAddAction(topic, m)
+		})
+
+		if err != nil {
+			return subs, err
+		}
+
+		subs = append(subs, sub)
+	}
+
+	return subs, nil
+}
+
+func (h *Hemera) callAddAction(topic string, m *Msg) {
+	pack := packet{}
+
+	// decoding hemera packet, packets which violate the encryption or signature options are rejected
+	if err := h.decodePacket(m, &pack, "add: "); IsSecurityError(err) {
+		reply := Reply{context: newIncomingContext(&pack), reply: m.Reply, hemera: h}
+		reply.Send(err)
+		return
+	}
+
+	// pubsub messages can be delivered to every matching handler
+	if pack.Request.RequestType == PubsubType && h.Opts.PubsubFanout {
+		if list := h.Router.LookupAll(pack.Pattern); len(list) > 0 {
+			for _, p := range list {
+				h.callHandler(p, &pack, m)
+			}
+			return
+		}
+	}
+
+	// Route on the raw pattern, the matched handler decides the request type
+	p := h.Router.Lookup(pack.Pattern)
+
+	if p == nil {
+		p = h.fallback(topic, pack.Pattern)
+	}
 
 	if p != nil {
-		// Get "Value" of the reply callback for the reflection Call
-		reply := Reply{
-			context: context,
-			pattern: p.Pattern,
-			reply:   m.Reply,
-			hemera:  h,
+		h.callHandler(p, &pack, m)
+		return
+	}
+
+	reply := Reply{
+		context: newIncomingContext(&pack),
+		pattern: pack.Pattern,
+		reply:   m.Reply,
+		hemera:  h,
+		sealed:  pack.sealed,
+	}
+
+	reply.Send(NewError(PatternNotFoundErrorName, "act: pattern could not be found", PatternNotFoundErrorCode))
+}
+
+// fallback returns the fallback handler of the topic as a pattern of the request
+func (h *Hemera) fallback(topic string, pattern interface{}) *router.PatternSet {
+	if h.fallbacks == nil {
+		return nil
+	}
+
+	fb := h.fallbacks.Lookup(map[string]interface{}{"topic": topic})
+
+	if fb == nil {
+		return nil
+	}
+
+	return &router.PatternSet{Pattern: pattern, Payload: fb.Payload}
+}
+
+// request returns the routed pattern with the payload fields, the pattern wins over the payload
+func (p *packet) request() interface{} {
+	pattern, ok := p.Pattern.(map[string]interface{})
+
+	if !ok || len(p.Payload) == 0 {
+		return p.Pattern
+	}
+
+	req := make(map[string]interface{}, len(pattern)+len(p.Payload))
+
+	for key, val := range p.Payload {
+		req[key] = val
+	}
+
+	for key, val := range pattern {
+		req[key] = val
+	}
+
+	return req
+}
+
+// guard checks the encryption, signature and token policy of a pattern
+func (h *Hemera) guard(ctx *Context, pack *packet, opts *AddOptions) *Error {
+	if opts.Encrypted && !pack.sealed {
+		return NewSecurityError("add: " + errNotEncrypted.Error())
+	}
+
+	if opts.Signed && !pack.signed {
+		return NewSecurityError("add: " + errNotSigned.Error())
+	}
+
+	return h.authenticate(ctx, opts)
+}
+
+// Name returns the function name of the callback for exported routing tables
+func (hd *handler) Name() string {
+	return router.FuncName(hd.cb)
+}
+
+func (h *Hemera) callHandler(p *router.PatternSet, pack *packet, m *Msg) {
+	context := newIncomingContext(pack)
+
+	oContextPtr := reflect.ValueOf(context)
+
+	reply := Reply{
+		context: context,
+		pattern: p.Pattern,
+		reply:   m.Reply,
+		hemera:  h,
+		sealed:  pack.sealed,
+	}
+
+	// patterns which were imported into the router directly have no handler
+	hd, ok := p.Payload.(*handler)
+
+	if !ok {
+		reply.Send(NewError(PatternNotFoundErrorName, "act: pattern could not be found", PatternNotFoundErrorCode))
+		return
+	}
+
+	if err := h.guard(context, pack, &hd.opts); err != nil {
+		reply.Send(err)
+		return
+	}
+
+	if l := hd.opts.RateLimiter; l != nil {
+		var key string
+
+		if hd.opts.RateLimitKey != "" {
+			key = fmt.Sprint(context.GetMeta(hd.opts.RateLimitKey))
 		}
 
-		oReplyPtr := reflect.ValueOf(reply)
-		cbValue := reflect.ValueOf(p.Payload)
+		if !l.Allow(key) {
+			reply.Send(NewRateLimitError("add: rate limit exceeded"))
+			return
+		}
+	}
 
-		// Get "Value" of the reply callback for the reflection Call
+	req := pack.request()
 
-		oPtr = reflect.ValueOf(oi)
+	if hd.opts.Schema != nil {
+		if errs := hd.opts.Schema.Validate(req); len(errs) > 0 {
+			details := make([]ErrorDetail, len(errs))
 
-		// array of arguments for the callback handler
-		var oV []reflect.Value
+			for i, e := range errs {
+				details[i] = ErrorDetail(e)
+			}
 
-		if numArgs == 2 {
-			oV = []reflect.Value{oPtr, oReplyPtr}
-		} else {
-			oV = []reflect.Value{oPtr, oReplyPtr, oContextPtr}
+			reply.Send(NewValidationError(details))
+			return
 		}
+	}
 
-		cbValue.Call(oV)
+	var oPtr reflect.Value
+
+	if hd.argType.Kind() != reflect.Ptr {
+		oPtr = reflect.New(hd.argType)
 	} else {
-		log.Fatal(NewErrorSimple("act: pattern could not be found"))
+		oPtr = reflect.New(hd.argType.Elem())
+	}
+
+	// Decode map to struct
+	err := mapstructure.Decode(req, oPtr.Interface())
+
+	if err != nil {
+		reply.Send(NewErrorSimple("add: " + err.Error()))
+		return
 	}
+
+	if hd.argType.Kind() != reflect.Ptr {
+		oPtr = oPtr.Elem()
+	}
+
+	oReplyPtr := reflect.ValueOf(reply)
+	cbValue := reflect.ValueOf(hd.cb)
+
+	// array of arguments for the callback handler
+	var oV []reflect.Value
+
+	if hd.numArgs == 2 {
+		oV = []reflect.Value{oPtr, oReplyPtr}
+	} else {
+		oV = []reflect.Value{oPtr, oReplyPtr, oContextPtr}
+	}
+
+	cbValue.Call(oV)
 }
 
-// Act is a method to send a message to a NATS subscriber which the specific topic
+// Act is a method to send a message to a NATS subscriber which the specific topic.
+// The arguments are the pattern, the result and optionally a *Context and ActOptions.
 func (h *Hemera) Act(args ...interface{}) *Context {
 	context := &Context{}
 
@@ -221,43 +658,55 @@ func (h *Hemera) Act(args ...interface{}) *Context {
 
 	var ctx *Context
 
-	if len(args) == 3 {
-		ctx = args[2].(*Context)
-	}
+	actOpts := ActOptions{StrictResult: h.Opts.StrictDecoding}
 
-	s := structs.New(p)
-	topicField := s.Field("Topic")
-
-	if topicField.IsZero() {
-		context.Error = NewErrorSimple("act: topic is required")
-		return context
+	for _, arg := range args[2:] {
+		switch a := arg.(type) {
+		case *Context:
+			ctx = a
+		case ActOption:
+			if err := a(&actOpts); err != nil {
+				context.Error = err
+				return context
+			}
+		default:
+			context.Error = NewErrorSimple("act: invalid argument")
+			return context
+		}
 	}
 
-	topic, ok := topicField.Value().(string)
+	topic, pattern, payload, metaField, delegateField, err := actPattern(p)
 
-	if !ok {
-		context.Error = NewErrorSimple("act: topic must be from type string")
+	if err != nil {
+		context.Error = err
 		return context
 	}
 
-	var metaField Meta
-	var delegateField Delegate
+	timeout := h.Opts.Timeout * time.Millisecond
 
-	if ctx == nil {
-		if field, ok := s.FieldOk("Meta"); ok {
-			metaField = field.Value().(Meta)
-		}
+	// the wait for a token is part of the timeout of the act
+	if l := h.Opts.ActRateLimiter; l != nil {
+		start := time.Now()
 
-		if field, ok := s.FieldOk("Delegate"); ok {
-			delegateField = field.Value().(Delegate)
+		if err := l.Wait(topic, timeout); err != nil {
+			context.Error = err
+			return context
 		}
-	} else {
-		metaField = ctx.Meta
-		delegateField = ctx.Delegate
+
+		timeout -= time.Since(start)
+	}
+
+	metaField, delegateField = propagate(ctx, metaField, delegateField)
+	delegateField, err = h.attachToken(delegateField)
+
+	if err != nil {
+		context.Error = err
+		return context
 	}
 
 	request := packet{
-		Pattern:  CleanPattern(s),
+		Pattern:  pattern,
+		Payload:  payload,
 		Meta:     metaField,
 		Delegate: delegateField,
 		Trace: Trace{
@@ -269,50 +718,125 @@ func (h *Hemera) Act(args ...interface{}) *Context {
 		},
 	}
 
-	data, err := jsoniter.Marshal(&request)
+	data, err := h.encodePacket(topic, &request, actOpts.Encrypt, "act: ")
+
+	if err != nil {
+		context.Error = err
+		return context
+	}
 
-	m, err := h.Conn.Request(topic, data, h.Opts.Timeout*time.Millisecond)
+	m, err := h.Transport.Request(topic, data, timeout)
 
 	if err != nil {
-		log.Fatal(err)
 		context.Error = err
 		return context
 	}
 
 	pack := packet{}
-	mErr := jsoniter.Unmarshal(m.Data, &pack)
+	mErr := h.decodePacket(m, &pack, "act: ")
 
 	if mErr != nil {
-		log.Fatal(mErr)
 		context.Error = mErr
 		return context
 	}
 
-	errResMap := mapstructure.Decode(pack.Result, out)
+	// the response to an encrypted request must be sealed, a plain error could be injected by anyone
+	if (actOpts.Encrypt || h.Opts.EncryptPackets) && !pack.sealed {
+		context.Error = NewSecurityError("act: response is not encrypted")
+	} else if pack.Error != nil {
+		context.Error = pack.Error
+	} else if err := decodeResult(pack.Result, out, actOpts); err != nil {
+		context.Error = err
+	}
 
-	if errResMap != nil {
-		panic(errResMap)
+	context.Trace = pack.Trace
+	// the delegate is not returned, it stays with the caller
+	context.Meta = pack.Meta
+	context.Delegate = delegateField
+
+	return context
+}
+
+// actPattern returns the topic, the cleaned pattern, the payload fields, meta and delegate of a struct or map pattern
+func actPattern(p interface{}) (string, interface{}, map[string]interface{}, Meta, Delegate, error) {
+	var meta Meta
+	var delegate Delegate
+
+	if m, ok := p.(map[string]interface{}); ok {
+		pattern := make(map[string]interface{})
+		topic := ""
+
+		for key, val := range m {
+			switch strings.ToLower(key) {
+			case "meta":
+				meta = toMetaMap(val)
+			case "delegate":
+				delegate = Delegate(toMetaMap(val))
+			case "topic":
+				topic, _ = val.(string)
+				pattern[key] = val
+			default:
+				pattern[key] = val
+			}
+		}
+
+		if topic == "" {
+			return "", nil, nil, nil, nil, NewErrorSimple("act: topic is required")
+		}
+
+		return topic, pattern, nil, meta, delegate, nil
+	}
+
+	s := structs.New(p)
+	topicField, ok := s.FieldOk("Topic")
+
+	if !ok || topicField.IsZero() {
+		return "", nil, nil, nil, nil, NewErrorSimple("act: topic is required")
 	}
 
-	responseError := pack.Error
+	topic, ok := topicField.Value().(string)
+
+	if !ok {
+		return "", nil, nil, nil, nil, NewErrorSimple("act: topic must be from type string")
+	}
+
+	if field, ok := s.FieldOk("Meta"); ok {
+		meta = field.Value().(Meta)
+	}
+
+	if field, ok := s.FieldOk("Delegate"); ok {
+		delegate = field.Value().(Delegate)
+	}
 
-	// create container for error
-	errorMsg := &Error{}
+	pattern := CleanPattern(s).(map[string]interface{})
+	var payload map[string]interface{}
 
-	if responseError != nil {
-		// Decode error map to struct
-		errErrMap := mapstructure.Decode(responseError, errorMsg)
+	// payload fields must not select a pattern on the receiver
+	for _, f := range s.Fields() {
+		if f.IsExported() && f.Tag("hemera") == "payload" {
+			if payload == nil {
+				payload = make(map[string]interface{})
+			}
 
-		if errErrMap != nil {
-			panic(errErrMap)
+			payload[f.Name()] = f.Value()
+			delete(pattern, f.Name())
 		}
 	}
 
-	context.Trace = pack.Trace
-	context.Meta = pack.Meta
-	context.Delegate = pack.Delegate
+	return topic, pattern, payload, meta, delegate, nil
+}
 
-	return context
+func toMetaMap(v interface{}) Meta {
+	switch m := v.(type) {
+	case Meta:
+		return m
+	case Delegate:
+		return Meta(m)
+	case map[string]interface{}:
+		return Meta(m)
+	}
+
+	return nil
 }
 
 // Dissect the cb Handler's signature
@@ -333,11 +857,13 @@ func ArgInfo(cb Handler) ([]reflect.Type, int) {
 	return argTypes, numArgs
 }
 
+// CleanPattern returns the fields of the pattern which are sent. Meta, delegate and
+// fields with the `hemera:"-"` tag are omitted.
 func CleanPattern(s *structs.Struct) interface{} {
 	var pattern = make(map[string]interface{})
 
 	for _, f := range s.Fields() {
-		if f.IsExported() {
+		if f.IsExported() && f.Tag("hemera") != "-" {
 			switch f.Value().(type) {
 			case Meta:
 			case Delegate:
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	jsoniter "github.com/json-iterator/go"
 	natsServer "github.com/nats-io/gnatsd/server"
 	gnatsd "github.com/nats-io/gnatsd/test"
 	nats "github.com/nats-io/go-nats"
@@ -31,6 +32,17 @@ func RunServerWithOptions(opts natsServer.Options) *natsServer.Server {
 	return gnatsd.RunServer(&opts)
 }
 
+// newTestHemera creates a hemera on the transport, the test fails when an option is invalid
+func newTestHemera(t *testing.T, transport Transport, options ...Option) Hemera {
+	h, err := CreateHemeraWithTransport(transport, options...)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
 type MathPattern struct {
 	Topic string
 	Cmd   string
@@ -150,3 +162,155 @@ func TestNoDuplicatesAllowed(t *testing.T) {
 	assert.Equal(errAdd.Error(), "add: duplicate pattern", "Should be not allowed to add duplicate patterns")
 
 }
+
+func TestAddRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := RunServerOnPort(TEST_PORT)
+	defer ts.Shutdown()
+
+	opts := reconnectOpts
+	nc, err := opts.Connect()
+	defer nc.Close()
+
+	if err != nil {
+		panic(err)
+	}
+
+	h, _ := CreateHemera(nc)
+
+	pattern := MathPattern{Topic: "math", Cmd: "add"}
+
+	h.Add(pattern, func(req *RequestPattern, reply Reply, context *Context) {
+		reply.Send(Response{Result: req.A + req.B})
+	}, RateLimit(NewRateLimiter(0, 1), ""))
+
+	requestPattern := RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}
+
+	ctx := h.Act(requestPattern, &Response{})
+	assert.Nil(ctx.Error, "First request should pass")
+
+	ctx = h.Act(requestPattern, &Response{})
+	assert.True(IsRateLimitError(ctx.Error), "Second request should be throttled")
+}
+
+type TenantRequestPattern struct {
+	Topic string
+	Cmd   string
+	A     int
+	B     int
+	Meta  Meta
+}
+
+func TestAddRateLimitByMeta(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := RunServerOnPort(TEST_PORT)
+	defer ts.Shutdown()
+
+	opts := reconnectOpts
+	nc, err := opts.Connect()
+	defer nc.Close()
+
+	if err != nil {
+		panic(err)
+	}
+
+	h, _ := CreateHemera(nc)
+
+	pattern := MathPattern{Topic: "math", Cmd: "add"}
+
+	h.Add(pattern, func(req *RequestPattern, reply Reply, context *Context) {
+		reply.Send(Response{Result: req.A + req.B})
+	}, RateLimit(NewRateLimiter(0, 1), "tenant"))
+
+	ctx := h.Act(TenantRequestPattern{Topic: "math", Cmd: "add", Meta: Meta{"tenant": "a"}}, &Response{})
+	assert.Nil(ctx.Error, "Tenant a should pass")
+
+	ctx = h.Act(TenantRequestPattern{Topic: "math", Cmd: "add", Meta: Meta{"tenant": "b"}}, &Response{})
+	assert.Nil(ctx.Error, "Tenant b should pass")
+
+	ctx = h.Act(TenantRequestPattern{Topic: "math", Cmd: "add", Meta: Meta{"tenant": "a"}}, &Response{})
+	assert.True(IsRateLimitError(ctx.Error), "Tenant a should be throttled")
+}
+
+func addHandler(req *RequestPattern, reply Reply) {
+	reply.Send(Response{Result: req.A + req.B})
+}
+
+func TestImport(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h := newTestHemera(t, mt)
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, addHandler)
+	h.Add(MathPattern{Topic: "calc", Cmd: "sum"}, addHandler, Priority(2), Description("sums"))
+
+	data, err := h.Router.MarshalJSON()
+	assert.Nil(err, "Should export the router")
+
+	et := NewMemoryTransport()
+	defer et.Close()
+
+	imported := newTestHemera(t, et)
+	imported.Add(MathPattern{Topic: "math", Cmd: "add"}, addHandler)
+
+	_, err = imported.Import(data)
+	assert.Equal(err.Error(), "import: pattern conflict, duplicate {Topic:math Cmd:add}", "Should reject a registered pattern")
+
+	imported = newTestHemera(t, et)
+	imported.Add(MathPattern{Topic: "math", Cmd: "mul"}, addHandler)
+
+	subs, err := imported.Import(data)
+	assert.Nil(err, "Should import the table")
+	assert.Equal(len(subs), 1, "Should subscribe the new topic")
+
+	res := &Response{}
+	ctx := imported.Act(RequestPattern{Topic: "calc", Cmd: "sum", A: 1, B: 2}, res)
+	assert.Nil(ctx.Error, "Should call the registered handler")
+	assert.Equal(res.Result, 3, "Should be 3")
+	assert.Equal(imported.Router.Lookup(map[string]interface{}{"topic": "calc", "cmd": "sum"}).Priority, 2, "Should keep the priority")
+
+	c, err := imported.Contract()
+	assert.Nil(err, "Should describe the imported patterns")
+	assert.Equal(len(c.Endpoints), 3, "Should contain all patterns")
+	assert.Equal(len(imported.Patterns().Patterns), 3, "Should list all patterns")
+
+	empty := newTestHemera(t, et)
+	_, err = empty.Import(data)
+	assert.Equal(err.Error(), "import: pattern 0: handler github.com/hemerajs/go-hemera.addHandler is not registered", "Should require the handlers")
+}
+
+func TestImportRouterPayloads(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h := newTestHemera(t, mt)
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, addHandler)
+
+	// the router resolves the names to payloads which are no handlers
+	err := h.Router.Import([]byte(`{"indexing": "insertion", "patterns": [{"fields": {"topic": "math", "cmd": "sub"}, "handler": "sub"}]}`), nil)
+	assert.Nil(err, "Should import the table")
+
+	ctx := h.Act(RequestPattern{Topic: "math", Cmd: "sub"}, &Response{})
+	assert.Equal(ctx.Error.(*Error).Name, PatternNotFoundErrorName, "Should not call a foreign payload")
+
+	for _, topic := range []string{ListTopic, ContractTopic} {
+		m, err := mt.Request(topic, []byte("{}"), time.Second)
+		assert.Nil(err, "Should answer "+topic)
+
+		res := struct {
+			Error *Error `json:"error"`
+		}{}
+		jsoniter.Unmarshal(m.Data, &res)
+		assert.Nil(res.Error, "Should skip foreign payloads")
+	}
+
+	c, _ := h.Contract()
+	assert.Equal(len(c.Endpoints), 1, "Should describe the patterns with a handler")
+	assert.Equal(len(h.Patterns().Patterns), 2, "Should list the imported pattern")
+}
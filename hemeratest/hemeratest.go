@@ -0,0 +1,94 @@
+// Package hemeratest provides utilities to test services and callers built on hemera
+// without copying server setup into every project.
+package hemeratest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hemerajs/go-hemera"
+	natsServer "github.com/nats-io/gnatsd/server"
+	gnatsd "github.com/nats-io/gnatsd/test"
+	nats "github.com/nats-io/go-nats"
+)
+
+// Harness is a Hemera instance whose received acts are recorded
+type Harness struct {
+	*hemera.Hemera
+	Recorder *Recorder
+	server   *natsServer.Server
+}
+
+// New create a Harness on top of an in-memory transport
+func New(options ...hemera.Option) (*Harness, error) {
+	return newHarness(hemera.NewMemoryTransport(), nil, options...)
+}
+
+// NewWithServer start an embedded NATS server on port and create a Harness connected to it
+func NewWithServer(port int, options ...hemera.Option) (*Harness, error) {
+	s := RunServerOnPort(port)
+
+	nc, err := ReconnectOptions(port).Connect()
+
+	if err != nil {
+		s.Shutdown()
+		return nil, err
+	}
+
+	return newHarness(hemera.NewNatsTransport(nc), s, options...)
+}
+
+// newHarness create the Harness, the transport and the server are closed when an option fails
+func newHarness(t hemera.Transport, s *natsServer.Server, options ...hemera.Option) (*Harness, error) {
+	rec := NewRecorder(t)
+	h, err := hemera.CreateHemeraWithTransport(rec, options...)
+
+	if err != nil {
+		t.Close()
+
+		if s != nil {
+			s.Shutdown()
+		}
+
+		return nil, err
+	}
+
+	return &Harness{Hemera: &h, Recorder: rec, server: s}, nil
+}
+
+// Stub register a pattern which replies with the response
+func (h *Harness) Stub(pattern, response interface{}) error {
+	return Stub(h.Hemera, pattern, response)
+}
+
+// StubError register a pattern which replies with the error
+func (h *Harness) StubError(pattern interface{}, err *hemera.Error) error {
+	return StubError(h.Hemera, pattern, err)
+}
+
+// Close the transport and the embedded server
+func (h *Harness) Close() {
+	h.Transport.Close()
+
+	if h.server != nil {
+		h.server.Shutdown()
+	}
+}
+
+// RunServerOnPort start an embedded NATS server
+func RunServerOnPort(port int) *natsServer.Server {
+	opts := gnatsd.DefaultTestOptions
+	opts.Port = port
+	return gnatsd.RunServer(&opts)
+}
+
+// ReconnectOptions returns the connection options for an embedded server on port
+func ReconnectOptions(port int) nats.Options {
+	return nats.Options{
+		Url:            fmt.Sprintf("nats://localhost:%d", port),
+		AllowReconnect: true,
+		MaxReconnect:   10,
+		ReconnectWait:  100 * time.Millisecond,
+		Timeout:        nats.DefaultTimeout,
+	}
+}
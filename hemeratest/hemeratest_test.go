@@ -0,0 +1,120 @@
+package hemeratest
+
+import (
+	"testing"
+
+	"github.com/hemerajs/go-hemera"
+	"github.com/stretchr/testify/assert"
+)
+
+const TEST_PORT = 8369
+
+type MathPattern struct {
+	Topic string
+	Cmd   string
+}
+
+type RequestPattern struct {
+	Topic    string
+	Cmd      string
+	A        int
+	B        int
+	Meta     hemera.Meta
+	Delegate hemera.Delegate
+}
+
+type Response struct {
+	Result int
+}
+
+func TestStub(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t)
+	defer h.Close()
+
+	h.Stub(MathPattern{Topic: "math", Cmd: "add"}, Response{Result: 3})
+
+	res := &Response{}
+	ctx := h.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, res)
+
+	assert.Nil(ctx.Error, "Should not fail")
+	assert.Equal(res.Result, 3, "Should reply the canned response")
+}
+
+func TestStubError(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t)
+	defer h.Close()
+
+	h.StubError(MathPattern{Topic: "math", Cmd: "add"}, hemera.NewError("MathError", "overflow", 500))
+
+	ctx := h.Act(RequestPattern{Topic: "math", Cmd: "add"}, &Response{})
+
+	assert.Equal(ctx.Error.Error(), "overflow", "Should reply the canned error")
+}
+
+func TestRecorder(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t)
+	defer h.Close()
+
+	h.Stub(MathPattern{Topic: "math", Cmd: "add"}, Response{Result: 3})
+	h.Stub(MathPattern{Topic: "math", Cmd: "sub"}, Response{Result: -1})
+
+	h.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2, Meta: hemera.Meta{"tenant": "a"}}, &Response{})
+	h.Act(RequestPattern{Topic: "math", Cmd: "sub", A: 1, B: 2, Delegate: hemera.Delegate{"user": "b"}}, &Response{})
+
+	call := h.Recorder.AssertReceived(t, RequestPattern{Topic: "math", Cmd: "add", A: 1})
+	assert.Equal(call.Meta["tenant"], "a", "Should record meta")
+
+	call = h.Recorder.AssertReceived(t, map[string]interface{}{"cmd": "sub"})
+	assert.Equal(call.Delegate["user"], "b", "Should record delegate")
+
+	h.Recorder.AssertNotReceived(t, MathPattern{Topic: "math", Cmd: "mul"})
+	h.Recorder.AssertCount(t, MathPattern{Topic: "math"}, 2)
+	h.Recorder.AssertOrder(t, MathPattern{Cmd: "add"}, MathPattern{Cmd: "sub"})
+
+	assert.False(h.Recorder.Find(MathPattern{Cmd: "sub"})[0].Matches(MathPattern{Cmd: "add"}), "Should not match")
+}
+
+func TestNewWithServer(t *testing.T) {
+	assert := assert.New(t)
+
+	h, err := NewWithServer(TEST_PORT)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer h.Close()
+
+	h.Stub(MathPattern{Topic: "math", Cmd: "add"}, Response{Result: 3})
+
+	res := &Response{}
+	h.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, res)
+
+	assert.Equal(res.Result, 3, "Should reply the canned response")
+	h.Recorder.AssertCount(t, MathPattern{Topic: "math", Cmd: "add"}, 1)
+}
+
+func newTestHarness(t *testing.T, options ...hemera.Option) *Harness {
+	h, err := New(options...)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+func TestNewOptionError(t *testing.T) {
+	assert := assert.New(t)
+
+	h, err := New(hemera.EncryptPackets(true))
+
+	assert.Nil(h, "Should not create a harness")
+	assert.Equal(err.Error(), "keyring is required", "Should return the error of the option")
+}
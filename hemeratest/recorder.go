@@ -0,0 +1,203 @@
+package hemeratest
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hemerajs/go-hemera"
+	jsoniter "github.com/json-iterator/go"
+)
+
+type (
+	// Call is an act which was received by a subscriber
+	Call struct {
+		Topic    string
+		Pattern  map[string]interface{}
+		Meta     hemera.Meta
+		Delegate hemera.Delegate
+		Time     time.Time
+	}
+	// Recorder is a Transport which records every act received by its subscribers
+	Recorder struct {
+		hemera.Transport
+		mu    sync.Mutex
+		calls []Call
+	}
+	callPacket struct {
+		Pattern  map[string]interface{} `json:"pattern"`
+		Meta     hemera.Meta            `json:"meta"`
+		Delegate hemera.Delegate        `json:"delegate"`
+	}
+)
+
+// NewRecorder wrap the transport
+func NewRecorder(t hemera.Transport) *Recorder {
+	return &Recorder{Transport: t}
+}
+
+func (r *Recorder) QueueSubscribe(subject, queue string, cb hemera.MsgHandler) (hemera.Subscription, error) {
+	return r.Transport.QueueSubscribe(subject, queue, func(m *hemera.Msg) {
+		pack := callPacket{}
+
+		if err := jsoniter.Unmarshal(m.Data, &pack); err == nil {
+			r.mu.Lock()
+			r.calls = append(r.calls, Call{
+				Topic:    m.Subject,
+				Pattern:  pack.Pattern,
+				Meta:     pack.Meta,
+				Delegate: pack.Delegate,
+				Time:     time.Now(),
+			})
+			r.mu.Unlock()
+		}
+
+		cb(m)
+	})
+}
+
+// Calls returns all received acts in the order of arrival
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Call(nil), r.calls...)
+}
+
+// Find returns all received acts which match the pattern
+func (r *Recorder) Find(pattern interface{}) []Call {
+	found := []Call{}
+
+	for _, c := range r.Calls() {
+		if c.Matches(pattern) {
+			found = append(found, c)
+		}
+	}
+
+	return found
+}
+
+// Reset forget all recorded acts
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.calls = nil
+	r.mu.Unlock()
+}
+
+// AssertReceived fails the test when no act matched the pattern and returns the first match
+func (r *Recorder) AssertReceived(t testing.TB, pattern interface{}) *Call {
+	t.Helper()
+
+	found := r.Find(pattern)
+
+	if len(found) == 0 {
+		t.Errorf("hemeratest: no act received for pattern %+v", pattern)
+		return nil
+	}
+
+	return &found[0]
+}
+
+// AssertNotReceived fails the test when an act matched the pattern
+func (r *Recorder) AssertNotReceived(t testing.TB, pattern interface{}) bool {
+	t.Helper()
+
+	if found := r.Find(pattern); len(found) > 0 {
+		t.Errorf("hemeratest: unexpected act received for pattern %+v: %+v", pattern, found[0].Pattern)
+		return false
+	}
+
+	return true
+}
+
+// AssertCount fails the test when the pattern was not received n times
+func (r *Recorder) AssertCount(t testing.TB, pattern interface{}, n int) bool {
+	t.Helper()
+
+	if found := r.Find(pattern); len(found) != n {
+		t.Errorf("hemeratest: expected %d acts for pattern %+v, received %d", n, pattern, len(found))
+		return false
+	}
+
+	return true
+}
+
+// AssertOrder fails the test when the patterns were not received in the given order.
+// Other acts may be received in between.
+func (r *Recorder) AssertOrder(t testing.TB, patterns ...interface{}) bool {
+	t.Helper()
+
+	i := 0
+
+	for _, c := range r.Calls() {
+		if i < len(patterns) && c.Matches(patterns[i]) {
+			i++
+		}
+	}
+
+	if i < len(patterns) {
+		t.Errorf("hemeratest: pattern %+v was not received in order", patterns[i])
+		return false
+	}
+
+	return true
+}
+
+// WaitFor blocks until the pattern was received or the timeout expired
+func (r *Recorder) WaitFor(pattern interface{}, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		if len(r.Find(pattern)) > 0 {
+			return true
+		}
+
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Matches returns true when every non-zero field of the pattern is part of the call.
+// The pattern is a struct or a map, fields are compared case-insensitive like they are decoded.
+// Meta and Delegate fields of the pattern are ignored.
+func (c Call) Matches(pattern interface{}) bool {
+	expected := toMap(pattern)
+	actual := toMap(c.Pattern)
+
+	for key, val := range expected {
+		if key == "meta" || key == "delegate" || val == nil || reflect.ValueOf(val).IsZero() {
+			continue
+		}
+
+		if !reflect.DeepEqual(actual[key], val) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// toMap normalize a pattern to its JSON representation with lowercase keys
+func toMap(p interface{}) map[string]interface{} {
+	m := map[string]interface{}{}
+
+	data, err := jsoniter.Marshal(p)
+
+	if err != nil {
+		return m
+	}
+
+	raw := map[string]interface{}{}
+	jsoniter.Unmarshal(data, &raw)
+
+	for key, val := range raw {
+		m[strings.ToLower(key)] = val
+	}
+
+	return m
+}
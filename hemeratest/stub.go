@@ -0,0 +1,35 @@
+package hemeratest
+
+import (
+	"reflect"
+
+	"github.com/hemerajs/go-hemera"
+)
+
+var replyType = reflect.TypeOf(hemera.Reply{})
+
+// Stub register a pattern which replies with the response.
+// The request is decoded into the type of the pattern.
+func Stub(h *hemera.Hemera, pattern, response interface{}) error {
+	return addStub(h, pattern, response)
+}
+
+// StubError register a pattern which replies with the error
+func StubError(h *hemera.Hemera, pattern interface{}, err *hemera.Error) error {
+	return addStub(h, pattern, err)
+}
+
+func addStub(h *hemera.Hemera, pattern, payload interface{}) error {
+	reqType := reflect.PtrTo(reflect.TypeOf(pattern))
+	fnType := reflect.FuncOf([]reflect.Type{reqType, replyType}, nil, false)
+
+	fn := reflect.MakeFunc(fnType, func(args []reflect.Value) []reflect.Value {
+		reply := args[1].Interface().(hemera.Reply)
+		reply.Send(payload)
+		return nil
+	})
+
+	_, err := h.Add(pattern, fn.Interface())
+
+	return err
+}
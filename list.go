@@ -0,0 +1,127 @@
+package hemera
+
+import (
+	"github.com/fatih/structs"
+)
+
+// ListTopic is the subject on which every instance answers with its registered patterns
+const ListTopic = "hemera.list"
+
+// PatternList is the response of an instance to a list request
+type PatternList struct {
+	ID       string        `json:"id"`
+	Patterns []interface{} `json:"patterns"`
+}
+
+// Patterns returns the registered patterns of the instance
+func (h *Hemera) Patterns() PatternList {
+	list := PatternList{ID: h.ID, Patterns: []interface{}{}}
+
+	for _, ps := range h.Router.List() {
+		// imported patterns are maps of their fields and matchers
+		if structs.IsStruct(ps.Pattern) {
+			list.Patterns = append(list.Patterns, CleanPattern(structs.New(ps.Pattern)))
+		} else {
+			list.Patterns = append(list.Patterns, ps.Pattern)
+		}
+	}
+
+	return list
+}
+
+// subscribeList answers list and contract requests, every instance receives them
+func (h *Hemera) subscribeList() error {
+	h.listMu.Lock()
+	defer h.listMu.Unlock()
+
+	if h.listSub != nil {
+		return nil
+	}
+
+	sub, err := h.answer(ListTopic, func() interface{} {
+		return h.Patterns()
+	})
+
+	if err != nil {
+		return err
+	}
+
+	contractSub, err := h.answer(ContractTopic, func() interface{} {
+		c, err := h.Contract()
+
+		if err != nil {
+			return NewErrorSimple("contract: " + err.Error())
+		}
+
+		return c
+	})
+
+	if err != nil {
+		sub.Unsubscribe()
+		return err
+	}
+
+	h.listSub = sub
+	h.contractSub = contractSub
+
+	return nil
+}
+
+// answer replies to every request of the topic with the result of fn. The requests
+// are protected like the most protected pattern because the answers describe all of them.
+func (h *Hemera) answer(topic string, fn func() interface{}) (Subscription, error) {
+	return h.Transport.QueueSubscribe(topic, "", func(m *Msg) {
+		if m.Reply == "" {
+			return
+		}
+
+		pack := packet{}
+		err := h.decodePacket(m, &pack, "add: ")
+
+		reply := Reply{
+			context: newIncomingContext(&pack),
+			reply:   m.Reply,
+			hemera:  h,
+			sealed:  pack.sealed,
+		}
+
+		if IsSecurityError(err) {
+			reply.Send(err)
+			return
+		}
+
+		opts := h.listOptions()
+
+		if err := h.guard(reply.context, &pack, &opts); err != nil {
+			reply.Send(err)
+			return
+		}
+
+		reply.Send(fn())
+	})
+}
+
+// listOptions returns the policy of list and contract requests, a token, encryption or
+// a signature is required when a pattern requires it
+func (h *Hemera) listOptions() AddOptions {
+	opts := AddOptions{}
+	patterns := h.Router.List()
+
+	if h.fallbacks != nil {
+		patterns = append(patterns, h.fallbacks.List()...)
+	}
+
+	for _, ps := range patterns {
+		hd, ok := ps.Payload.(*handler)
+
+		if !ok {
+			continue
+		}
+
+		opts.Authenticated = opts.Authenticated || hd.opts.Authenticated
+		opts.Encrypted = opts.Encrypted || hd.opts.Encrypted
+		opts.Signed = opts.Signed || hd.opts.Signed
+	}
+
+	return opts
+}
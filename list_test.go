@@ -0,0 +1,105 @@
+package hemera
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hemerajs/go-hemera/auth"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListPatterns(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h, _ := CreateHemeraWithTransport(mt)
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply) {})
+	h.Add(MathPattern{Topic: "math", Cmd: "sub"}, func(req *RequestPattern, reply Reply) {})
+
+	m, err := mt.Request(ListTopic, []byte("{}"), time.Second)
+	assert.Nil(err, "Should answer list requests")
+
+	res := struct {
+		Result PatternList `json:"result"`
+	}{}
+	jsoniter.Unmarshal(m.Data, &res)
+
+	assert.Equal(res.Result.ID, h.ID, "Should contain the instance id")
+	assert.Equal(len(res.Result.Patterns), 2, "Should contain all patterns")
+}
+
+func TestListConcurrentAdd(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h, _ := CreateHemeraWithTransport(mt)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+			h.Add(MathPattern{Topic: "math", Cmd: strconv.Itoa(i)}, func(req *RequestPattern, reply Reply) {})
+		}(i)
+	}
+
+	wg.Wait()
+
+	answers := make(chan *Msg, 8)
+	mt.QueueSubscribe("list.inbox", "", func(m *Msg) { answers <- m })
+	mt.publish(ListTopic, "list.inbox", []byte("{}"))
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(len(answers), 1, "Should subscribe once")
+}
+
+func TestListProtected(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	key := []byte("secret")
+	h, _ := CreateHemeraWithTransport(mt, VerifyTokens(auth.NewHMACVerifier(key)))
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply) {}, Authenticated())
+
+	list := PatternList{}
+	ctx := h.Act(map[string]interface{}{"topic": ListTopic}, &list)
+	assert.True(IsUnauthorizedError(ctx.Error), "Should require a token like the patterns")
+
+	ctx = h.Act(map[string]interface{}{"topic": ContractTopic}, &Contract{})
+	assert.True(IsUnauthorizedError(ctx.Error), "Should require a token for the contract")
+
+	token, _ := auth.SignHMAC(map[string]interface{}{"sub": "u1"}, "HS256", key)
+	ctx = h.Act(map[string]interface{}{"topic": ListTopic}, &list, &Context{Delegate: Delegate{TokenDelegateKey: token}})
+	assert.Nil(ctx.Error, "Should answer with a valid token")
+	assert.Equal(len(list.Patterns), 1, "Should contain all patterns")
+
+	keyring, _ := NewKeyring("k1", map[string][]byte{"k1": []byte("0123456789abcdef")})
+	et := NewMemoryTransport()
+	defer et.Close()
+
+	s, _ := CreateHemeraWithTransport(et, Encryption(keyring), EncryptPackets(true))
+	s.Add(MathPattern{Topic: "calc", Cmd: "add"}, func(req *RequestPattern, reply Reply) {})
+
+	m, err := et.Request(ContractTopic, []byte("{}"), time.Second)
+	assert.Nil(err, "Should answer")
+	assert.NotContains(string(m.Data), "calc", "Should not leak the contract in plaintext")
+
+	client, _ := CreateHemeraWithTransport(et, Encryption(keyring), EncryptPackets(true))
+	c := Contract{}
+	ctx = client.Act(map[string]interface{}{"topic": ContractTopic}, &c)
+	assert.Nil(ctx.Error, "Should answer an encrypted request")
+	assert.Equal(c.Endpoints[0].Topic, "calc", "Should be `calc`")
+}
@@ -0,0 +1,222 @@
+package hemera
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTransportClosed is returned when a closed Transport is used
+var ErrTransportClosed = NewErrorSimple("transport: connection closed")
+
+type (
+	// MemoryTransport is an in-process Transport which delivers messages through channels.
+	// It is meant for testing services without a NATS server.
+	MemoryTransport struct {
+		// Latency is added to the delivery of every message
+		Latency time.Duration
+		// FailureRate is the probability between 0 and 1 that a message is dropped silently
+		FailureRate float64
+		// Fail is called before a message is sent, a non nil error is returned to the sender
+		Fail func(subject string) error
+
+		mu     sync.RWMutex
+		subs   map[string][]*memorySubscription
+		closed bool
+		inbox  uint64
+	}
+	memorySubscription struct {
+		transport *MemoryTransport
+		subject   string
+		queue     string
+		msgs      chan delivery
+		done      chan struct{}
+		once      sync.Once
+	}
+	// delivery is a queued message which is passed to the callback at its due time
+	delivery struct {
+		msg *Msg
+		due time.Time
+	}
+)
+
+// NewMemoryTransport create a new in-process Transport
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{subs: make(map[string][]*memorySubscription)}
+}
+
+func (t *MemoryTransport) QueueSubscribe(subject, queue string, cb MsgHandler) (Subscription, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil, ErrTransportClosed
+	}
+
+	sub := &memorySubscription{
+		transport: t,
+		subject:   subject,
+		queue:     queue,
+		msgs:      make(chan delivery, 1024),
+		done:      make(chan struct{}),
+	}
+
+	t.subs[subject] = append(t.subs[subject], sub)
+
+	// deliver messages in order like a NATS subscription, the latency
+	// delays the queue instead of single messages
+	go func() {
+		for {
+			select {
+			case d := <-sub.msgs:
+				if wait := time.Until(d.due); wait > 0 {
+					select {
+					case <-time.After(wait):
+					case <-sub.done:
+						return
+					}
+				}
+				cb(d.msg)
+			case <-sub.done:
+				return
+			}
+		}
+	}()
+
+	return sub, nil
+}
+
+func (t *MemoryTransport) Request(subject string, data []byte, timeout time.Duration) (*Msg, error) {
+	inbox := "_INBOX." + strconv.FormatUint(atomic.AddUint64(&t.inbox, 1), 10)
+	response := make(chan *Msg, 1)
+
+	sub, err := t.QueueSubscribe(inbox, "", func(m *Msg) {
+		select {
+		case response <- m:
+		default:
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer sub.Unsubscribe()
+
+	if err := t.publish(subject, inbox, data); err != nil {
+		return nil, err
+	}
+
+	select {
+	case m := <-response:
+		return m, nil
+	case <-time.After(timeout):
+		return nil, ErrRequestTimeout
+	}
+}
+
+func (t *MemoryTransport) Publish(subject string, data []byte) error {
+	return t.publish(subject, "", data)
+}
+
+func (t *MemoryTransport) publish(subject, reply string, data []byte) error {
+	if t.Fail != nil {
+		if err := t.Fail(subject); err != nil {
+			return err
+		}
+	}
+
+	targets, err := t.targets(subject)
+
+	if err != nil {
+		return err
+	}
+
+	if t.FailureRate > 0 && rand.Float64() < t.FailureRate {
+		return nil
+	}
+
+	d := delivery{msg: &Msg{Subject: subject, Reply: reply, Data: data}}
+
+	if t.Latency > 0 {
+		d.due = time.Now().Add(t.Latency)
+	}
+
+	// a full queue blocks the sender but not the transport, callbacks can subscribe and publish
+	for _, sub := range targets {
+		select {
+		case sub.msgs <- d:
+		case <-sub.done:
+		}
+	}
+
+	return nil
+}
+
+// targets returns the subscribers of the subject which receive a message,
+// every plain subscriber and one member of every queue group
+func (t *MemoryTransport) targets(subject string) ([]*memorySubscription, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.closed {
+		return nil, ErrTransportClosed
+	}
+
+	targets := []*memorySubscription{}
+	groups := make(map[string][]*memorySubscription)
+
+	for _, sub := range t.subs[subject] {
+		if sub.queue == "" {
+			targets = append(targets, sub)
+		} else {
+			groups[sub.queue] = append(groups[sub.queue], sub)
+		}
+	}
+
+	for _, members := range groups {
+		targets = append(targets, members[rand.Intn(len(members))])
+	}
+
+	return targets, nil
+}
+
+func (t *MemoryTransport) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, subs := range t.subs {
+		for _, sub := range subs {
+			sub.once.Do(func() { close(sub.done) })
+		}
+	}
+
+	t.subs = make(map[string][]*memorySubscription)
+	t.closed = true
+}
+
+func (s *memorySubscription) Unsubscribe() error {
+	t := s.transport
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	subs := t.subs[s.subject]
+
+	for i, sub := range subs {
+		if sub == s {
+			t.subs[s.subject] = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+
+	if len(t.subs[s.subject]) == 0 {
+		delete(t.subs, s.subject)
+	}
+
+	s.once.Do(func() { close(s.done) })
+
+	return nil
+}
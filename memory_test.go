@@ -0,0 +1,437 @@
+package hemera
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryTransportAct(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h, _ := CreateHemeraWithTransport(mt)
+
+	pattern := MathPattern{Topic: "math", Cmd: "add"}
+
+	h.Add(pattern, func(req *RequestPattern, reply Reply, context *Context) {
+		reply.Send(Response{Result: req.A + req.B})
+	})
+
+	res := &Response{}
+	ctx := h.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, res)
+
+	assert.Nil(ctx.Error, "Should not fail")
+	assert.Equal(res.Result, 3, "Should be 3")
+}
+
+func TestMemoryTransportQueueGroup(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	received := make(chan string, 10)
+
+	mt.QueueSubscribe("math", "math", func(m *Msg) { received <- "a" })
+	mt.QueueSubscribe("math", "math", func(m *Msg) { received <- "b" })
+	mt.QueueSubscribe("math", "", func(m *Msg) { received <- "plain" })
+
+	mt.Publish("math", []byte("{}"))
+
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(len(received), 2, "Should deliver once per queue group and to every plain subscriber")
+}
+
+func TestMemoryTransportLatency(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	mt.Latency = 20 * time.Millisecond
+
+	mt.QueueSubscribe("echo", "echo", func(m *Msg) {
+		mt.Publish(m.Reply, m.Data)
+	})
+
+	start := time.Now()
+	m, err := mt.Request("echo", []byte("ping"), time.Second)
+
+	assert.Nil(err, "Should not fail")
+	assert.Equal(string(m.Data), "ping", "Should echo the request")
+	assert.True(time.Since(start) >= 40*time.Millisecond, "Should delay request and response")
+}
+
+func TestMemoryTransportLatencyOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	mt.Latency = time.Millisecond
+
+	received := make(chan string, 100)
+
+	mt.QueueSubscribe("seq", "", func(m *Msg) {
+		received <- string(m.Data)
+	})
+
+	for i := 0; i < 100; i++ {
+		mt.Publish("seq", []byte(strconv.Itoa(i)))
+	}
+
+	for i := 0; i < 100; i++ {
+		select {
+		case data := <-received:
+			assert.Equal(data, strconv.Itoa(i), "Should deliver in order")
+		case <-time.After(time.Second):
+			t.Fatal("Should receive every message")
+		}
+	}
+}
+
+func TestMemoryTransportBackpressure(t *testing.T) {
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	mt.QueueSubscribe("echo", "echo", func(m *Msg) {
+		mt.Publish(m.Reply, m.Data)
+	})
+
+	// every message makes a nested request while the queue is full
+	done := make(chan struct{}, 2048)
+
+	mt.QueueSubscribe("flood", "flood", func(m *Msg) {
+		mt.Request("echo", m.Data, time.Second)
+		done <- struct{}{}
+	})
+
+	go func() {
+		for i := 0; i < 2048; i++ {
+			mt.Publish("flood", []byte("ping"))
+		}
+	}()
+
+	for i := 0; i < 2048; i++ {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Should not deadlock on a full queue")
+		}
+	}
+}
+
+func TestMemoryTransportFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h, _ := CreateHemeraWithTransport(mt, Timeout(50))
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply, context *Context) {
+		reply.Send(Response{Result: req.A + req.B})
+	})
+
+	mt.Fail = func(subject string) error {
+		return errors.New("network down")
+	}
+
+	ctx := h.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, &Response{})
+	assert.Equal(ctx.Error.Error(), "network down", "Should return the transport error")
+
+	mt.Fail = nil
+	mt.FailureRate = 1
+
+	ctx = h.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, &Response{})
+	assert.Equal(ctx.Error, ErrRequestTimeout, "Should time out when the message is lost")
+}
+
+func TestActMapPattern(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h, _ := CreateHemeraWithTransport(mt)
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply, context *Context) {
+		reply.Send(Response{Result: req.A + req.B})
+	})
+
+	res := &Response{}
+	ctx := h.Act(map[string]interface{}{"topic": "math", "cmd": "add", "a": 1, "b": 2, "meta": map[string]interface{}{"k": "v"}}, res)
+
+	assert.Nil(ctx.Error, "Should not fail")
+	assert.Equal(res.Result, 3, "Should be 3")
+
+	ctx = h.Act(map[string]interface{}{"cmd": "add"}, res)
+	assert.Equal(ctx.Error.Error(), "act: topic is required", "Should require a topic")
+}
+
+type UserFilter struct {
+	Role string
+}
+
+type UserPattern struct {
+	Topic  string
+	Filter UserFilter
+}
+
+func TestActNestedPattern(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h, _ := CreateHemeraWithTransport(mt, IndexingStrategy(DepthIndexing))
+
+	h.Add(UserPattern{Topic: "user", Filter: UserFilter{Role: "admin"}}, func(req *UserPattern, reply Reply) {
+		reply.Send("admin")
+	})
+
+	h.Add(UserPattern{Topic: "user"}, func(req *UserPattern, reply Reply) {
+		reply.Send("user")
+	})
+
+	var res string
+	h.Act(UserPattern{Topic: "user", Filter: UserFilter{Role: "admin"}}, &res)
+	assert.Equal(res, "admin", "Should route on nested fields")
+
+	h.Act(UserPattern{Topic: "user", Filter: UserFilter{Role: "guest"}}, &res)
+	assert.Equal(res, "user", "Should fall back to the topic")
+}
+
+type SubPattern struct {
+	Topic string
+	Cmd   string
+	X     int
+}
+
+func TestRouteBeforeDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h, _ := CreateHemeraWithTransport(mt)
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply) {
+		reply.Send(Response{Result: req.A + req.B})
+	})
+
+	h.Add(MathPattern{Topic: "math", Cmd: "sub"}, func(req SubPattern, reply Reply) {
+		reply.Send(Response{Result: -req.X})
+	})
+
+	res := &Response{}
+	h.Act(map[string]interface{}{"topic": "math", "cmd": "sub", "x": 5}, res)
+	assert.Equal(res.Result, -5, "Should decode into the request type of the matched handler")
+
+	h.Act(map[string]interface{}{"topic": "math", "cmd": "add", "a": 1, "b": 2}, res)
+	assert.Equal(res.Result, 3, "Should be 3")
+}
+
+func TestStrictPatterns(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h, _ := CreateHemeraWithTransport(mt, StrictPatterns(true))
+
+	_, err := h.Add(MathPattern{Topic: "math"}, func(req *RequestPattern, reply Reply) {})
+	assert.Nil(err, "Should add the pattern")
+
+	_, err = h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply) {})
+	assert.Contains(err.Error(), "add: pattern conflict, shadowed", "Should reject shadowed patterns")
+
+	h, _ = CreateHemeraWithTransport(mt)
+
+	h.Add(MathPattern{Topic: "math"}, func(req *RequestPattern, reply Reply) {})
+	_, err = h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply) {})
+	assert.Nil(err, "Should allow more specific patterns")
+}
+
+func TestFallback(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h, _ := CreateHemeraWithTransport(mt)
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply) {
+		reply.Send(Response{Result: req.A + req.B})
+	})
+
+	res := &Response{}
+	ctx := h.Act(MathPattern{Topic: "math", Cmd: "sub"}, res)
+	assert.Equal(ctx.Error.(*Error).Name, PatternNotFoundErrorName, "Should reply with a PatternNotFoundError")
+
+	_, err := h.Fallback("math", func(req *RequestPattern, reply Reply) {
+		reply.Send(Response{Result: -1})
+	})
+	assert.Nil(err, "Should add the fallback")
+
+	_, err = h.Fallback("math", func(req *RequestPattern, reply Reply) {})
+	assert.Equal(err.Error(), "add: duplicate fallback", "Should reject a second fallback")
+
+	ctx = h.Act(MathPattern{Topic: "math", Cmd: "sub"}, res)
+	assert.Nil(ctx.Error, "Should have no error")
+	assert.Equal(res.Result, -1, "Should be handled by the fallback")
+
+	h.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, res)
+	assert.Equal(res.Result, 3, "Should prefer the matched pattern")
+
+	ft := NewMemoryTransport()
+	defer ft.Close()
+
+	fb := newTestHemera(t, ft)
+	fb.Fallback("order", func(req *RequestPattern, reply Reply) {})
+
+	m, err := ft.Request(ContractTopic, []byte("{}"), time.Second)
+	assert.Nil(err, "Should answer contract requests with fallbacks only")
+
+	c := struct {
+		Result Contract `json:"result"`
+	}{}
+	jsoniter.Unmarshal(m.Data, &c)
+	assert.Equal(len(c.Result.Endpoints), 1, "Should contain the fallback")
+	assert.True(c.Result.Endpoints[0].Fallback, "Should be a fallback")
+
+	_, err = ft.Request(ListTopic, []byte("{}"), time.Second)
+	assert.Nil(err, "Should answer list requests with fallbacks only")
+}
+
+func TestAddPriority(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h, _ := CreateHemeraWithTransport(mt, IndexingStrategy(DepthIndexing))
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply) {
+		reply.Send(Response{Result: req.A + req.B})
+	})
+
+	h.Add(MathPattern{Topic: "math"}, func(req *RequestPattern, reply Reply) {
+		reply.Send(Response{Result: 0})
+	}, Priority(1))
+
+	res := &Response{}
+	h.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, res)
+	assert.Equal(res.Result, 0, "Should prefer the higher priority")
+}
+
+type TaggedPattern struct {
+	Topic  string
+	Cmd    string
+	Active bool   `hemera:"match"`
+	Note   string `hemera:"payload"`
+	Secret string `hemera:"-"`
+}
+
+func TestAddConcurrentDuplicate(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h, _ := CreateHemeraWithTransport(mt)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply) {})
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	failed := 0
+
+	for err := range errs {
+		if err != nil {
+			assert.Equal(err.Error(), "add: duplicate pattern", "Should reject the duplicate")
+			failed++
+		}
+	}
+
+	assert.Equal(failed, 19, "Should add the pattern once")
+	assert.Equal(len(h.Router.List()), 1, "Should contain one pattern")
+}
+
+func TestStructTags(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h, _ := CreateHemeraWithTransport(mt, IndexingStrategy(DepthIndexing))
+
+	h.Add(TaggedPattern{Topic: "user", Cmd: "list"}, func(req *TaggedPattern, reply Reply) {
+		reply.Send(req.Note + req.Secret + ":inactive")
+	})
+
+	h.Add(TaggedPattern{Topic: "user", Cmd: "list", Active: true}, func(req *TaggedPattern, reply Reply) {
+		reply.Send(req.Note + req.Secret + ":active")
+	})
+
+	var res string
+	h.Act(TaggedPattern{Topic: "user", Cmd: "list", Note: "note", Secret: "secret"}, &res)
+	assert.Equal(res, "note:inactive", "Should send payload fields and match zero values")
+
+	h.Act(TaggedPattern{Topic: "user", Cmd: "list", Active: true}, &res)
+	assert.Equal(res, ":active", "Should be `:active`")
+}
+
+type NotePattern struct {
+	Topic string
+	Cmd   string
+	Note  string
+}
+
+func TestPayloadRouting(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h := newTestHemera(t, mt, IndexingStrategy(DepthIndexing))
+
+	h.Add(TaggedPattern{Topic: "user", Cmd: "list"}, func(req *TaggedPattern, reply Reply) {
+		reply.Send(req.Note + ":user")
+	})
+
+	h.Add(NotePattern{Topic: "user", Cmd: "list", Note: "admin"}, func(req *NotePattern, reply Reply) {
+		reply.Send(req.Note + ":admin")
+	})
+
+	var res string
+	ctx := h.Act(TaggedPattern{Topic: "user", Cmd: "list", Note: "admin"}, &res)
+	assert.Nil(ctx.Error, "Should have no error")
+	assert.Equal(res, "admin:user", "Should not route on the payload field")
+
+	ctx = h.Act(NotePattern{Topic: "user", Cmd: "list", Note: "admin"}, &res)
+	assert.Nil(ctx.Error, "Should have no error")
+	assert.Equal(res, "admin:admin", "Should route on the untagged field")
+}
@@ -0,0 +1,54 @@
+package hemera
+
+import (
+	"github.com/nats-io/nuid"
+)
+
+// PubsubFanout is an Option to invoke every matching handler of a pubsub message
+// in priority order instead of only the best match
+func PubsubFanout(enabled bool) Option {
+	return func(o *Options) error {
+		o.PubsubFanout = enabled
+		return nil
+	}
+}
+
+// Publish sends the pattern to the subscribers of its topic without waiting for a response.
+// Meta and delegate of the optional context are propagated like by Act.
+func (h *Hemera) Publish(p interface{}, ctx ...*Context) error {
+	topic, pattern, payload, metaField, delegateField, err := actPattern(p)
+
+	if err != nil {
+		return err
+	}
+
+	if len(ctx) > 0 {
+		metaField, delegateField = propagate(ctx[0], metaField, delegateField)
+	}
+
+	if delegateField, err = h.attachToken(delegateField); err != nil {
+		return err
+	}
+
+	request := packet{
+		Pattern:  pattern,
+		Payload:  payload,
+		Meta:     metaField,
+		Delegate: delegateField,
+		Trace: Trace{
+			TraceID: nuid.Next(),
+		},
+		Request: request{
+			ID:          nuid.Next(),
+			RequestType: PubsubType,
+		},
+	}
+
+	data, err := h.encodePacket(topic, &request, false, "act: ")
+
+	if err != nil {
+		return err
+	}
+
+	return h.Transport.Publish(topic, data)
+}
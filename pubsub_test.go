@@ -0,0 +1,80 @@
+package hemera
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type EventPattern struct {
+	Topic string
+	Cmd   string
+	Type  string
+}
+
+func publish(t *testing.T, options ...Option) []string {
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h, _ := CreateHemeraWithTransport(mt, options...)
+
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	called := []string{}
+
+	handler := func(name string) Handler {
+		return func(req *EventPattern, reply Reply) {
+			mu.Lock()
+			called = append(called, name)
+			mu.Unlock()
+			reply.Send(nil)
+			wg.Done()
+		}
+	}
+
+	h.Add(EventPattern{Topic: "order", Cmd: "created"}, handler("created"))
+	h.Add(EventPattern{Topic: "order", Type: "vip"}, handler("vip"))
+	h.Add(EventPattern{Topic: "order", Cmd: "deleted"}, handler("deleted"))
+
+	wg.Add(1)
+
+	if h.Opts.PubsubFanout {
+		wg.Add(1)
+	}
+
+	err := h.Publish(EventPattern{Topic: "order", Cmd: "created", Type: "vip"})
+	assert.Nil(t, err, "Should publish")
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handlers were not called")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	return called
+}
+
+func TestPublish(t *testing.T) {
+	assert := assert.New(t)
+
+	called := publish(t)
+	assert.Equal(called, []string{"created"}, "Should call the best match")
+}
+
+func TestPublishFanout(t *testing.T) {
+	assert := assert.New(t)
+
+	called := publish(t, PubsubFanout(true))
+	assert.Equal(called, []string{"created", "vip"}, "Should call every match in priority order")
+}
@@ -0,0 +1,141 @@
+package hemera
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultRateLimitKeys is the number of keys a limiter tracks before it evicts the least recently used bucket
+const DefaultRateLimitKeys = 10000
+
+type (
+	// RateLimiter is a token bucket limiter. Every key owns its own bucket
+	// so a single limiter can throttle many callers independently.
+	RateLimiter struct {
+		rate    float64
+		burst   float64
+		maxKeys int
+		mu      sync.Mutex
+		buckets map[string]*list.Element
+		// buckets by last use, the most recently used first
+		lru *list.List
+		now func() time.Time
+	}
+	tokenBucket struct {
+		key    string
+		tokens float64
+		last   time.Time
+	}
+)
+
+// NewRateLimiter create a limiter which refills rate tokens per second up to burst tokens
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		maxKeys: DefaultRateLimitKeys,
+		buckets: make(map[string]*list.Element),
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of key and reports whether one was available
+func (l *RateLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(key)
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+// Wait blocks until a token of key is available and takes it. When the token
+// is not available within max a RateLimitError is returned without waiting.
+func (l *RateLimiter) Wait(key string, max time.Duration) error {
+	l.mu.Lock()
+
+	b := l.bucket(key)
+
+	if b.tokens < 1 && l.rate <= 0 {
+		l.mu.Unlock()
+		return NewRateLimitError("act: rate limit exceeded")
+	}
+
+	b.tokens--
+
+	// the token is reserved, sleep until the bucket is refilled to zero
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / l.rate * float64(time.Second))
+	}
+
+	// release the reservation, the backlog of waiting callers is bounded by max
+	if delay > max {
+		b.tokens++
+		l.mu.Unlock()
+		return NewRateLimitError("act: rate limit exceeded")
+	}
+
+	l.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+
+	return nil
+}
+
+// bucket returns the refilled bucket of key, the caller must hold the lock
+func (l *RateLimiter) bucket(key string) *tokenBucket {
+	now := l.now()
+
+	if e, ok := l.buckets[key]; ok {
+		l.lru.MoveToFront(e)
+
+		b := e.Value.(*tokenBucket)
+		b.tokens = l.refill(b, now)
+		b.last = now
+
+		return b
+	}
+
+	l.evict(now)
+
+	b := &tokenBucket{key: key, tokens: l.burst, last: now}
+	l.buckets[key] = l.lru.PushFront(b)
+
+	return b
+}
+
+// refill returns the tokens of the bucket at now
+func (l *RateLimiter) refill(b *tokenBucket, now time.Time) float64 {
+	return math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+}
+
+// evict drops idle buckets which are refilled and equal to a new bucket
+// and the least recently used buckets above the maximum number of keys
+func (l *RateLimiter) evict(now time.Time) {
+	for e := l.lru.Back(); e != nil; e = l.lru.Back() {
+		b := e.Value.(*tokenBucket)
+
+		if l.lru.Len() < l.maxKeys && l.refill(b, now) < l.burst {
+			return
+		}
+
+		l.lru.Remove(e)
+		delete(l.buckets, b.key)
+	}
+}
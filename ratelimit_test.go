@@ -0,0 +1,99 @@
+package hemera
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterRefill(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	l := NewRateLimiter(10, 2)
+	l.now = func() time.Time { return now }
+
+	assert.True(l.Allow("a"), "Should allow burst")
+	assert.True(l.Allow("a"), "Should allow burst")
+	assert.False(l.Allow("a"), "Should reject when bucket is empty")
+	assert.True(l.Allow("b"), "Should limit keys independently")
+
+	now = now.Add(100 * time.Millisecond)
+
+	assert.True(l.Allow("a"), "Should allow after refill")
+	assert.False(l.Allow("a"), "Should reject when bucket is empty")
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	assert := assert.New(t)
+
+	l := NewRateLimiter(100, 1)
+
+	start := time.Now()
+	assert.Nil(l.Wait("a", time.Second), "Should take the token")
+	assert.Nil(l.Wait("a", time.Second), "Should wait for the token")
+
+	assert.True(time.Since(start) >= 10*time.Millisecond, "Should wait for the next token")
+
+	l = NewRateLimiter(0, 1)
+	assert.Nil(l.Wait("a", time.Second), "Should take the burst")
+	assert.True(IsRateLimitError(l.Wait("a", time.Second)), "Should not wait for a limiter without refill")
+
+	l = NewRateLimiter(10, 1)
+	assert.Nil(l.Wait("a", 0), "Should take the burst")
+
+	start = time.Now()
+	for i := 0; i < 100; i++ {
+		assert.True(IsRateLimitError(l.Wait("a", 50*time.Millisecond)), "Should not wait longer than max")
+	}
+	assert.True(time.Since(start) < 50*time.Millisecond, "Should fail without waiting")
+
+	assert.Nil(l.Wait("a", 150*time.Millisecond), "Should release the reservations of failed waits")
+	assert.True(time.Since(start) < 150*time.Millisecond, "Should wait for the first token only")
+}
+
+func TestRateLimiterEviction(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	l := NewRateLimiter(10, 2)
+	l.now = func() time.Time { return now }
+	l.maxKeys = 3
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		l.Allow(key)
+	}
+
+	assert.Equal(len(l.buckets), 3, "Should evict above the maximum number of keys")
+	assert.Nil(l.buckets["a"], "Should evict the least recently used key")
+
+	now = now.Add(time.Second)
+	l.Allow("e")
+
+	assert.Equal(len(l.buckets), 1, "Should evict refilled buckets")
+
+	l.Allow("e")
+	assert.False(l.Allow("e"), "Should keep the bucket of an active key")
+}
+
+func TestActRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h := newTestHemera(t, mt, ActRateLimit(1, 1), Timeout(100))
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply) {
+		reply.Send(Response{Result: req.A + req.B})
+	})
+
+	ctx := h.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, &Response{})
+	assert.Nil(ctx.Error, "Should take the burst")
+
+	start := time.Now()
+	ctx = h.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, &Response{})
+	assert.True(IsRateLimitError(ctx.Error), "Should not wait longer than the timeout")
+	assert.True(time.Since(start) < 100*time.Millisecond, "Should fail without waiting")
+}
@@ -0,0 +1,148 @@
+// Package record captures hemera traffic into newline-delimited JSON files and
+// replays recorded acts against another instance.
+package record
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hemerajs/go-hemera"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// Inbound messages are received from the transport
+	Inbound Direction = "in"
+	// Outbound messages are sent to the transport
+	Outbound Direction = "out"
+
+	// KindRequest is an act which expects a response
+	KindRequest Kind = "request"
+	// KindResponse is the response of a request
+	KindResponse Kind = "response"
+	// KindPublish is a message published without expecting a response
+	KindPublish Kind = "publish"
+	// KindMessage is a message received by a subscriber
+	KindMessage Kind = "message"
+)
+
+type (
+	Direction string
+	Kind      string
+	// Entry is a single line of a recording
+	Entry struct {
+		ID        uint64          `json:"id"`
+		Ref       uint64          `json:"ref,omitempty"`
+		Time      time.Time       `json:"time"`
+		Direction Direction       `json:"direction"`
+		Kind      Kind            `json:"kind"`
+		Subject   string          `json:"subject"`
+		Reply     string          `json:"reply,omitempty"`
+		Packet    json.RawMessage `json:"packet"`
+	}
+	// Recorder is a Transport which writes every packet to w
+	Recorder struct {
+		hemera.Transport
+		w   io.Writer
+		mu  sync.Mutex
+		seq uint64
+		err error
+	}
+)
+
+// NewRecorder wrap the transport and write the recording to w
+func NewRecorder(t hemera.Transport, w io.Writer) *Recorder {
+	return &Recorder{Transport: t, w: w}
+}
+
+// NewFileRecorder wrap the transport and write the recording to the file at path
+func NewFileRecorder(t hemera.Transport, path string) (*Recorder, error) {
+	f, err := os.Create(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRecorder(t, f), nil
+}
+
+func (r *Recorder) QueueSubscribe(subject, queue string, cb hemera.MsgHandler) (hemera.Subscription, error) {
+	return r.Transport.QueueSubscribe(subject, queue, func(m *hemera.Msg) {
+		r.write(Inbound, KindMessage, 0, m.Subject, m.Reply, m.Data)
+		cb(m)
+	})
+}
+
+func (r *Recorder) Request(subject string, data []byte, timeout time.Duration) (*hemera.Msg, error) {
+	id := r.write(Outbound, KindRequest, 0, subject, "", data)
+
+	m, err := r.Transport.Request(subject, data, timeout)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r.write(Inbound, KindResponse, id, m.Subject, m.Reply, m.Data)
+
+	return m, nil
+}
+
+func (r *Recorder) Publish(subject string, data []byte) error {
+	r.write(Outbound, KindPublish, 0, subject, "", data)
+
+	return r.Transport.Publish(subject, data)
+}
+
+// Close the transport and the underlying writer
+func (r *Recorder) Close() {
+	r.Transport.Close()
+
+	if c, ok := r.w.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// Err returns the first error which occurred while writing the recording
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+func (r *Recorder) write(direction Direction, kind Kind, ref uint64, subject, reply string, data []byte) uint64 {
+	e := Entry{
+		ID:        atomic.AddUint64(&r.seq, 1),
+		Ref:       ref,
+		Time:      time.Now(),
+		Direction: direction,
+		Kind:      kind,
+		Subject:   subject,
+		Reply:     reply,
+		Packet:    json.RawMessage(data),
+	}
+
+	// packets which are no valid JSON are recorded as string
+	if !jsoniter.Valid(data) {
+		e.Packet, _ = jsoniter.Marshal(string(data))
+	}
+
+	line, err := jsoniter.Marshal(&e)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err == nil {
+		_, err = r.w.Write(append(line, '\n'))
+	}
+
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+
+	return e.ID
+}
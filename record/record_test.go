@@ -0,0 +1,165 @@
+package record
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/hemerajs/go-hemera"
+	"github.com/stretchr/testify/assert"
+)
+
+type MathPattern struct {
+	Topic string
+	Cmd   string
+}
+
+type RequestPattern struct {
+	Topic string
+	Cmd   string
+	A     int
+	B     int
+}
+
+type Response struct {
+	Result int
+}
+
+func record(t *testing.T, result func(req *RequestPattern) int) []Entry {
+	buf := &bytes.Buffer{}
+	rec := NewRecorder(hemera.NewMemoryTransport(), buf)
+	defer rec.Close()
+
+	h, _ := hemera.CreateHemeraWithTransport(rec)
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply hemera.Reply) {
+		reply.Send(Response{Result: result(req)})
+	})
+
+	h.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, &Response{})
+	h.Act(RequestPattern{Topic: "math", Cmd: "add", A: 2, B: 2}, &Response{})
+
+	entries, err := ReadEntries(buf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return entries
+}
+
+func TestRecorder(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := record(t, func(req *RequestPattern) int { return req.A + req.B })
+
+	kinds := []Kind{}
+
+	for _, e := range entries {
+		kinds = append(kinds, e.Kind)
+	}
+
+	assert.Contains(kinds, KindRequest, "Should record requests")
+	assert.Contains(kinds, KindMessage, "Should record received messages")
+	assert.Contains(kinds, KindPublish, "Should record replies")
+	assert.Contains(kinds, KindResponse, "Should record responses")
+
+	assert.Equal(entries[0].Direction, Outbound, "Should record the direction")
+	assert.Equal(entries[0].Subject, "math", "Should record the subject")
+}
+
+func TestReplay(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := record(t, func(req *RequestPattern) int { return req.A + req.B })
+
+	mt := hemera.NewMemoryTransport()
+	defer mt.Close()
+
+	h, _ := hemera.CreateHemeraWithTransport(mt)
+
+	// the target multiplies instead
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply hemera.Reply) {
+		reply.Send(Response{Result: req.A * req.B})
+	})
+
+	results := Replay(&h, entries)
+
+	assert.Equal(len(results), 2, "Should replay every request")
+	assert.Equal(results[0].Diff, []string{"result.Result: 3 != 2"}, "Should report the difference")
+	assert.Empty(results[1].Diff, "Should match")
+}
+
+func TestReplayServerSide(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := hemera.NewMemoryTransport()
+	defer mt.Close()
+
+	// only the server records its traffic
+	buf := &bytes.Buffer{}
+	server, _ := hemera.CreateHemeraWithTransport(NewRecorder(mt, buf))
+
+	server.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply hemera.Reply) {
+		reply.Send(Response{Result: req.A + req.B})
+	})
+
+	client, _ := hemera.CreateHemeraWithTransport(mt)
+	client.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, &Response{})
+	client.Act(RequestPattern{Topic: "math", Cmd: "add", A: 2, B: 2}, &Response{})
+
+	entries, err := ReadEntries(buf)
+	assert.Nil(err, "Should read the recording")
+
+	tt := hemera.NewMemoryTransport()
+	defer tt.Close()
+
+	h, _ := hemera.CreateHemeraWithTransport(tt)
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply hemera.Reply) {
+		reply.Send(Response{Result: req.A * req.B})
+	})
+
+	results := Replay(&h, entries)
+
+	assert.Equal(len(results), 2, "Should replay every received request")
+	assert.Equal(results[0].Request.Kind, KindMessage, "Should replay the received message")
+	assert.Equal(results[0].Diff, []string{"result.Result: 3 != 2"}, "Should compare with the published reply")
+	assert.Empty(results[1].Diff, "Should match")
+}
+
+func TestReplayEnvelope(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := record(t, func(req *RequestPattern) int { return req.A + req.B })
+
+	mt := hemera.NewMemoryTransport()
+	defer mt.Close()
+
+	_, priv, _ := ed25519.GenerateKey(nil)
+	// the target signs its replies
+	h, _ := hemera.CreateHemeraWithTransport(mt, hemera.SignPackets(&hemera.Signer{KeyID: "s1", Key: priv}))
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply hemera.Reply) {
+		reply.Send(Response{Result: req.A + req.B})
+	})
+
+	results := Replay(&h, entries)
+
+	assert.Equal(len(results), 2, "Should replay every request")
+	assert.Equal(results[0].Error, ErrEnvelope, "Should not compare the signed reply")
+	assert.Nil(results[0].Diff, "Should not diff")
+
+	// the recorded response was sealed
+	for i, e := range entries {
+		if e.Kind == KindResponse {
+			entries[i].Packet = []byte(`{"sealed":{"kid":"k1","nonce":"AA==","ciphertext":"AA=="}}`)
+		}
+	}
+
+	results = Replay(&h, entries)
+
+	assert.Equal(results[0].Error, ErrEnvelope, "Should not compare the sealed response")
+	assert.Nil(results[0].Recorded, "Should not decode the sealed response")
+	assert.Nil(results[0].Replayed, "Should not replay the request")
+}
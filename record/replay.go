@@ -0,0 +1,218 @@
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/hemerajs/go-hemera"
+	jsoniter "github.com/json-iterator/go"
+)
+
+type (
+	// Result is the outcome of a single replayed request
+	Result struct {
+		Request  Entry
+		Recorded interface{}
+		Replayed interface{}
+		Diff     []string
+		Error    error
+	}
+	// responsePacket is the result and error of a plain response
+	responsePacket struct {
+		Result interface{} `json:"result"`
+		Error  interface{} `json:"error"`
+	}
+)
+
+var (
+	// ErrEnvelope is the error of a response which was sealed or signed, the recording
+	// holds the envelope and its content can't be compared
+	ErrEnvelope = errors.New("record: sealed or signed response can't be compared")
+
+	envelopePrefixes = [][]byte{[]byte(`{"sealed":`), []byte(`{"packet":`)}
+)
+
+// ReadEntries reads a recording
+func ReadEntries(r io.Reader) ([]Entry, error) {
+	entries := []Entry{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+
+		if len(line) == 0 {
+			continue
+		}
+
+		e := Entry{}
+
+		if err := jsoniter.Unmarshal(line, &e); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, scanner.Err()
+}
+
+// ReadFile reads the recording at path
+func ReadFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return ReadEntries(f)
+}
+
+// Replay re-issues every recorded request against the target and compares
+// the result and error of the responses. Requests of a client side recording are
+// compared with the recorded responses, requests which were received by a server
+// with the replies it published to their reply subject.
+func Replay(h *hemera.Hemera, entries []Entry) []Result {
+	responses := make(map[uint64]Entry)
+	replies := make(map[string]Entry)
+
+	for _, e := range entries {
+		switch {
+		case e.Kind == KindResponse:
+			responses[e.Ref] = e
+		case e.Kind == KindPublish && e.Direction == Outbound:
+			if _, ok := replies[e.Subject]; !ok {
+				replies[e.Subject] = e
+			}
+		}
+	}
+
+	results := []Result{}
+	// a recording of both sides contains every request as sent and received
+	sent := make(map[string]bool)
+
+	for _, e := range entries {
+		var recorded Entry
+		var ok bool
+
+		switch {
+		case e.Kind == KindRequest:
+			sent[e.Subject+"\x00"+string(e.Packet)] = true
+			recorded, ok = responses[e.ID]
+		case e.Kind == KindMessage && e.Reply != "":
+			if sent[e.Subject+"\x00"+string(e.Packet)] {
+				continue
+			}
+			recorded, ok = replies[e.Reply]
+		default:
+			continue
+		}
+
+		res := Result{Request: e}
+
+		if ok {
+			if res.Recorded, res.Error = decodeResponse(recorded.Packet); res.Error != nil {
+				results = append(results, res)
+				continue
+			}
+		}
+
+		m, err := h.Transport.Request(e.Subject, e.Packet, h.Opts.Timeout*time.Millisecond)
+
+		if err != nil {
+			res.Error = err
+		} else if res.Replayed, res.Error = decodeResponse(m.Data); res.Error == nil {
+			res.Diff = Diff(res.Recorded, res.Replayed)
+		}
+
+		results = append(results, res)
+	}
+
+	return results
+}
+
+// decodeResponse returns the result and error of a response, envelopes are reported
+// as an error instead of comparing their empty result
+func decodeResponse(data []byte) (interface{}, error) {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+
+	for _, prefix := range envelopePrefixes {
+		if bytes.HasPrefix(trimmed, prefix) {
+			return nil, ErrEnvelope
+		}
+	}
+
+	pack := responsePacket{}
+
+	if err := jsoniter.Unmarshal(data, &pack); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"result": pack.Result, "error": pack.Error}, nil
+}
+
+// Diff returns the paths in which the decoded JSON values a and b differ
+func Diff(a, b interface{}) []string {
+	return diff("", a, b, []string{})
+}
+
+func diff(path string, a, b interface{}, diffs []string) []string {
+	am, aok := a.(map[string]interface{})
+	bm, bok := b.(map[string]interface{})
+
+	if aok && bok {
+		keys := []string{}
+
+		for key := range am {
+			keys = append(keys, key)
+		}
+
+		for key := range bm {
+			if _, ok := am[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			diffs = diff(join(path, key), am[key], bm[key], diffs)
+		}
+
+		return diffs
+	}
+
+	al, aok := a.([]interface{})
+	bl, bok := b.([]interface{})
+
+	if aok && bok && len(al) == len(bl) {
+		for i := range al {
+			diffs = diff(join(path, fmt.Sprint(i)), al[i], bl[i], diffs)
+		}
+
+		return diffs
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		diffs = append(diffs, fmt.Sprintf("%s: %v != %v", path, a, b))
+	}
+
+	return diffs
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
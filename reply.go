@@ -1,7 +1,6 @@
 package hemera
 
 import (
-	"github.com/json-iterator/go"
 	"github.com/nats-io/nuid"
 )
 
@@ -10,12 +9,19 @@ type Reply struct {
 	pattern interface{}
 	context *Context
 	reply   string
+	// the response is encrypted
+	sealed bool
 }
 
 func (r *Reply) Send(payload interface{}) {
+	// pubsub messages don't expect a response
+	if r.reply == "" {
+		return
+	}
+
 	response := packet{
 		Pattern: r.pattern,
-		Meta:    r.context.Meta,
+		Meta:    r.context.CopyMeta(),
 		Trace:   r.context.Trace,
 		Request: request{
 			ID:          nuid.Next(),
@@ -24,13 +30,20 @@ func (r *Reply) Send(payload interface{}) {
 	}
 
 	// Check if error or message was passed
-	he, ok := payload.(Error)
-	if ok {
+	switch he := payload.(type) {
+	case Error:
 		response.Error = &he
-	} else {
+	case *Error:
+		response.Error = he
+	default:
 		response.Result = payload
 	}
 
-	data, _ := jsoniter.Marshal(&response)
-	r.hemera.Conn.Publish(r.reply, data)
+	data, err := r.hemera.encodePacket(r.reply, &response, r.sealed, "add: ")
+
+	if err != nil {
+		return
+	}
+
+	r.hemera.Transport.Publish(r.reply, data)
 }
@@ -0,0 +1,114 @@
+package router
+
+import "fmt"
+
+const (
+	// ConflictDuplicate is reported when a pattern with the same fields exists
+	ConflictDuplicate ConflictKind = "duplicate"
+	// ConflictShadowed is reported when an existing pattern wins every request of the new pattern
+	ConflictShadowed ConflictKind = "shadowed"
+	// ConflictShadows is reported when the new pattern wins every request of an existing pattern
+	ConflictShadows ConflictKind = "shadows"
+	// ConflictAmbiguous is reported when a request can match both patterns with the same weight
+	ConflictAmbiguous ConflictKind = "ambiguous"
+)
+
+type (
+	ConflictKind string
+	// Conflict describes how a new pattern overlaps with a registered pattern
+	Conflict struct {
+		Kind    ConflictKind
+		Pattern *PatternSet
+	}
+)
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s %+v", c.Kind, c.Pattern.Pattern)
+}
+
+// Conflicts reports the registered patterns which overlap with the pattern if it were added
+func (r *Router) Conflicts(pattern interface{}) []Conflict {
+	return r.ConflictsWithPriority(pattern, 0)
+}
+
+// ConflictsWithPriority reports the conflicts of a pattern which is added with the priority
+func (r *Router) ConflictsWithPriority(pattern interface{}, priority int) []Conflict {
+	ps := r.convertToPatternSet(pattern, true)
+	ps.Priority = priority
+
+	// a new pattern is always inserted last
+	ps.order = int(^uint(0) >> 1)
+
+	if !r.IsDeep {
+		ps.Weight = ps.order
+	}
+
+	conflicts := []Conflict{}
+
+	for _, e := range r.List() {
+		switch {
+		case samePattern(e, ps):
+			conflicts = append(conflicts, Conflict{Kind: ConflictDuplicate, Pattern: e})
+		case !compatible(e, ps):
+		case covers(e, ps) && r.before(e, ps):
+			conflicts = append(conflicts, Conflict{Kind: ConflictShadowed, Pattern: e})
+		case covers(ps, e) && r.before(ps, e):
+			conflicts = append(conflicts, Conflict{Kind: ConflictShadows, Pattern: e})
+		case r.IsDeep && e.Priority == ps.Priority && e.Weight == ps.Weight && len(e.Matchers) == len(ps.Matchers):
+			conflicts = append(conflicts, Conflict{Kind: ConflictAmbiguous, Pattern: e})
+		}
+	}
+
+	return conflicts
+}
+
+// covers check if every request which matches b also matches a
+func covers(a, b *PatternSet) bool {
+	for key, val := range a.Fields {
+		if b.Fields[key] != val {
+			return false
+		}
+	}
+
+	for key, m := range a.Matchers {
+		if val, ok := b.Fields[key]; ok {
+			if !m.Match(val) {
+				return false
+			}
+			continue
+		}
+
+		o, ok := b.Matchers[key]
+
+		if !ok {
+			return false
+		}
+
+		if _, any := m.(anyValue); !any && fmt.Sprintf("%T:%v", o, o) != fmt.Sprintf("%T:%v", m, m) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compatible check if a request could match both patterns
+func compatible(a, b *PatternSet) bool {
+	for key, val := range a.Fields {
+		if o, ok := b.Fields[key]; ok && o != val {
+			return false
+		}
+
+		if m, ok := b.Matchers[key]; ok && !m.Match(val) {
+			return false
+		}
+	}
+
+	for key, m := range a.Matchers {
+		if val, ok := b.Fields[key]; ok && !m.Match(val) {
+			return false
+		}
+	}
+
+	return true
+}
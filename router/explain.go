@@ -0,0 +1,124 @@
+package router
+
+import (
+	"fmt"
+)
+
+type (
+	// Explanation describes how a Lookup was resolved
+	Explanation struct {
+		Fields     PatternFields
+		Buckets    []ExplainBucket
+		Candidates []ExplainCandidate
+		Match      *PatternSet
+	}
+	// ExplainBucket is a group of patterns with the same keys which was consulted
+	ExplainBucket struct {
+		Keys   []string
+		Weight int
+		Size   int
+		Reason string
+	}
+	// ExplainCandidate is a pattern which was compared with the request
+	ExplainCandidate struct {
+		Pattern *PatternSet
+		Matched bool
+		Reason  string
+	}
+)
+
+// Explain runs a Lookup and reports the consulted buckets, every candidate
+// and why it did or did not match. Unlike Lookup every bucket is consulted.
+func (r *Router) Explain(p interface{}) *Explanation {
+	idx := r.load()
+	ps := r.convertToPatternSet(p, false)
+	e := &Explanation{Fields: ps.Fields}
+
+	sc := r.scratch(p)
+	defer scratchPool.Put(sc)
+
+	candidates := PatternSets{}
+	visited := make(map[*PatternSet]bool)
+
+	for _, g := range idx.groups {
+		b := ExplainBucket{Keys: g.keys, Weight: g.weight, Size: g.size}
+
+		if h, ok := sc.hash(g.keys); ok {
+			n := len(candidates)
+
+			for _, e := range g.root.leaf(h) {
+				if !visited[e.ps] {
+					visited[e.ps] = true
+					candidates = append(candidates, e.ps)
+				}
+			}
+
+			if n == len(candidates) {
+				b.Reason = "no pattern with the values of the request"
+			}
+		} else {
+			for _, key := range g.keys {
+				if _, ok := sc.get(key); !ok {
+					b.Reason = fmt.Sprintf("field %s is missing", key)
+					break
+				}
+			}
+		}
+
+		e.Buckets = append(e.Buckets, b)
+	}
+
+	candidates = append(candidates, idx.wildcards...)
+	r.sortPatternSets(candidates)
+
+	for _, pattern := range candidates {
+		reason := mismatch(ps.Fields, pattern)
+		c := ExplainCandidate{Pattern: pattern, Matched: reason == "", Reason: reason}
+
+		if c.Matched {
+			c.Reason = "matched"
+
+			if e.Match == nil {
+				e.Match = pattern
+				c.Reason = "selected"
+			}
+		}
+
+		e.Candidates = append(e.Candidates, c)
+	}
+
+	return e
+}
+
+// mismatch returns the reason why the fields don't match p or an empty string
+func mismatch(fields PatternFields, p *PatternSet) string {
+	for key, val := range p.Fields {
+		o, ok := fields[key]
+
+		if !ok {
+			return fmt.Sprintf("field %s is missing", key)
+		}
+
+		if o != val {
+			return fmt.Sprintf("field %s: %v != %v", key, o, val)
+		}
+	}
+
+	for key, m := range p.Matchers {
+		o, ok := fields[key]
+
+		if !ok && matchAbsent(m) {
+			continue
+		}
+
+		if !ok {
+			return fmt.Sprintf("field %s is missing", key)
+		}
+
+		if !m.Match(o) {
+			return fmt.Sprintf("field %s: %v does not match %v", key, o, m)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,336 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+)
+
+const (
+	DepthIndexing     = "depth"
+	InsertionIndexing = "insertion"
+)
+
+type (
+	// Table is the serializable form of all registered patterns
+	Table struct {
+		Indexing string       `json:"indexing"`
+		Patterns []TableEntry `json:"patterns"`
+	}
+	// TableEntry is a registered pattern with the name of its payload
+	TableEntry struct {
+		Fields   PatternFields          `json:"fields"`
+		Matchers map[string]MatcherSpec `json:"matchers,omitempty"`
+		Weight   int                    `json:"weight"`
+		Priority int                    `json:"priority"`
+		Handler  string                 `json:"handler,omitempty"`
+	}
+	// MatcherSpec describes a matcher, Type is one of any, zero, glob, regexp, set or range
+	MatcherSpec struct {
+		Type   string        `json:"type"`
+		Value  string        `json:"value,omitempty"`
+		Values []interface{} `json:"values,omitempty"`
+		Min    *float64      `json:"min,omitempty"`
+		Max    *float64      `json:"max,omitempty"`
+	}
+	// Namer is implemented by payloads which have a name in the exported table
+	Namer interface {
+		Name() string
+	}
+)
+
+// Export returns all patterns by insertion order
+func (r *Router) Export() (*Table, error) {
+	t := &Table{Indexing: InsertionIndexing, Patterns: []TableEntry{}}
+
+	if r.IsDeep {
+		t.Indexing = DepthIndexing
+	}
+
+	for _, ps := range r.List() {
+		e, err := ps.TableEntry()
+
+		if err != nil {
+			return nil, err
+		}
+
+		t.Patterns = append(t.Patterns, e)
+	}
+
+	return t, nil
+}
+
+// TableEntry returns the serializable form of the pattern
+func (ps *PatternSet) TableEntry() (TableEntry, error) {
+	e := TableEntry{
+		Fields:   make(PatternFields, len(ps.Fields)),
+		Weight:   ps.Weight,
+		Priority: ps.Priority,
+		Handler:  payloadName(ps.Payload),
+	}
+
+	for key, val := range ps.Fields {
+		e.Fields[key] = val
+	}
+
+	if len(ps.Matchers) > 0 {
+		e.Matchers = make(map[string]MatcherSpec, len(ps.Matchers))
+	}
+
+	for key, m := range ps.Matchers {
+		spec, err := matcherSpec(m)
+
+		if err != nil {
+			return e, fmt.Errorf("router: field %s: %v", key, err)
+		}
+
+		e.Matchers[key] = spec
+	}
+
+	return e, nil
+}
+
+// MarshalJSON encodes the exported table
+func (r *Router) MarshalJSON() ([]byte, error) {
+	t, err := r.Export()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonNumber.Marshal(t)
+}
+
+// Import adds the patterns of an exported table in their order. The payload of a pattern
+// is returned by resolve for the handler name, without resolve the name is the payload.
+func (r *Router) Import(data []byte, resolve func(name string) (interface{}, error)) error {
+	t, err := DecodeTable(data)
+
+	if err != nil {
+		return err
+	}
+
+	// a router which was declared without NewRouter takes the indexing of the table
+	r.mu.Lock()
+	if r.snapshot.Load() == nil && t.Indexing != "" {
+		r.IsDeep = t.Indexing == DepthIndexing
+	}
+	r.mu.Unlock()
+
+	if (t.Indexing == DepthIndexing) != r.IsDeep && t.Indexing != "" {
+		return fmt.Errorf("router: table with %s indexing can't be imported", t.Indexing)
+	}
+
+	type pending struct {
+		pattern  map[string]interface{}
+		payload  interface{}
+		priority int
+	}
+
+	patterns := []pending{}
+
+	// resolve all patterns before the first one is added
+	for i, e := range t.Patterns {
+		pattern, err := e.Pattern()
+
+		if err != nil {
+			return fmt.Errorf("router: pattern %d %v", i, err)
+		}
+
+		p := pending{pattern: pattern, payload: e.Handler, priority: e.Priority}
+
+		if resolve != nil {
+			payload, err := resolve(e.Handler)
+
+			if err != nil {
+				return fmt.Errorf("router: pattern %d: %v", i, err)
+			}
+
+			p.payload = payload
+		}
+
+		patterns = append(patterns, p)
+	}
+
+	for _, p := range patterns {
+		r.AddWithPriority(p.pattern, p.payload, p.priority)
+	}
+
+	return nil
+}
+
+// DecodeTable decodes an exported table, numbers keep their precision
+func DecodeTable(data []byte) (*Table, error) {
+	t := &Table{}
+
+	if err := jsonNumber.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("router: %v", err)
+	}
+
+	return t, nil
+}
+
+// Pattern returns the map pattern of the entry with the matchers of its specs
+func (e TableEntry) Pattern() (map[string]interface{}, error) {
+	pattern := make(map[string]interface{}, len(e.Fields)+len(e.Matchers))
+
+	for key, val := range e.Fields {
+		pattern[key] = val
+	}
+
+	for key, spec := range e.Matchers {
+		m, err := spec.Matcher()
+
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", key, err)
+		}
+
+		pattern[key] = m
+	}
+
+	return pattern, nil
+}
+
+// UnmarshalJSON imports the table, the handler names become the payloads
+func (r *Router) UnmarshalJSON(data []byte) error {
+	return r.Import(data, nil)
+}
+
+// Matcher creates the matcher of the spec
+func (s MatcherSpec) Matcher() (Matcher, error) {
+	switch s.Type {
+	case "any":
+		return Any, nil
+	case "zero":
+		return Zero, nil
+	case "glob":
+		return Glob(s.Value), nil
+	case "regexp":
+		return NewRegexp(s.Value)
+	case "set":
+		return Set(s.Values), nil
+	case "range":
+		r := Range{Min: math.Inf(-1), Max: math.Inf(1)}
+
+		if s.Min != nil {
+			r.Min = *s.Min
+		}
+
+		if s.Max != nil {
+			r.Max = *s.Max
+		}
+
+		return r, nil
+	}
+
+	return nil, fmt.Errorf("unknown matcher type %q", s.Type)
+}
+
+func matcherSpec(m Matcher) (MatcherSpec, error) {
+	switch v := m.(type) {
+	case anyValue:
+		return MatcherSpec{Type: "any"}, nil
+	case zeroValue:
+		return MatcherSpec{Type: "zero"}, nil
+	case Glob:
+		return MatcherSpec{Type: "glob", Value: string(v)}, nil
+	case Regexp:
+		return MatcherSpec{Type: "regexp", Value: v.String()}, nil
+	case Set:
+		values := make([]interface{}, 0, len(v))
+
+		for _, m := range v.members() {
+			values = append(values, m.iface())
+		}
+
+		return MatcherSpec{Type: "set", Values: values}, nil
+	case Range:
+		spec := MatcherSpec{Type: "range"}
+
+		if !math.IsInf(v.Min, -1) {
+			spec.Min = &v.Min
+		}
+
+		if !math.IsInf(v.Max, 1) {
+			spec.Max = &v.Max
+		}
+
+		return spec, nil
+	}
+
+	return MatcherSpec{}, fmt.Errorf("matcher %T can't be exported", m)
+}
+
+// payloadName returns the name of a Namer, a func, a fmt.Stringer or a string
+func payloadName(payload interface{}) string {
+	switch v := payload.(type) {
+	case nil:
+		return ""
+	case Namer:
+		return v.Name()
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	if v := reflect.ValueOf(payload); v.Kind() == reflect.Func {
+		return FuncName(payload)
+	}
+
+	return ""
+}
+
+// FuncName returns the name of a function e.g. `main.addHandler`
+func FuncName(fn interface{}) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+
+	return ""
+}
+
+// Diff returns the patterns of a which are not part of b and the patterns of b which
+// are not part of a. Patterns are compared by fields, matchers, priority and handler.
+func Diff(a, b *Table) (removed []TableEntry, added []TableEntry) {
+	keys := func(t *Table) map[string]int {
+		m := make(map[string]int)
+
+		for _, e := range t.Patterns {
+			m[e.key()]++
+		}
+
+		return m
+	}
+
+	ka, kb := keys(a), keys(b)
+
+	for _, e := range a.Patterns {
+		if k := e.key(); kb[k] > 0 {
+			kb[k]--
+		} else {
+			removed = append(removed, e)
+		}
+	}
+
+	for _, e := range b.Patterns {
+		if k := e.key(); ka[k] > 0 {
+			ka[k]--
+		} else {
+			added = append(added, e)
+		}
+	}
+
+	return removed, added
+}
+
+// key identifies an entry independent of its weight
+func (e TableEntry) key() string {
+	e.Weight = 0
+	// map keys are sorted by encoding/json
+	b, _ := json.Marshal(e)
+
+	return string(b)
+}
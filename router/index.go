@@ -0,0 +1,290 @@
+package router
+
+import (
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// index is an immutable snapshot of the registered patterns. Exact patterns are
+// grouped by their set of keys, a lookup probes every group with a single hash of
+// the request values. Patterns with matchers which can't be indexed are scanned after the groups.
+type index struct {
+	// ordered by the indexing strategy so that a lookup can stop early
+	groups    []*group
+	byKeys    map[string]*group
+	wildcards PatternSets
+	size      int
+}
+
+// group holds the indexed patterns with the same keys by the hash of their values.
+// The residual keys belong to range and zero matchers which are checked after the hash matched.
+type group struct {
+	keys     []string
+	residual []string
+	weight   int
+	// lowest insertion order of the group, it is kept after removals
+	first int
+	// highest priority of the group, it is kept after removals
+	priority int
+	// number of entries
+	size int
+	root *node
+}
+
+// entry is a pattern in a leaf, patterns with sets have an entry for every combination of members
+type entry struct {
+	values []value
+	ps     *PatternSet
+	// the first entry of a pattern
+	primary bool
+}
+
+// node is a node of a persistent hash trie. Inserts and removals copy the path
+// to the modified leaf, all other nodes are shared between snapshots.
+type node struct {
+	bitmap   uint32
+	children []*node
+	// leaf
+	hash    uint64
+	entries []entry
+}
+
+const (
+	trieBits = 5
+	trieMask = 1<<trieBits - 1
+)
+
+func groupID(keys, residual []string) string {
+	return strings.Join(keys, "\x00") + "\x01" + strings.Join(residual, "\x00")
+}
+
+func (n *node) isLeaf() bool {
+	return n.children == nil
+}
+
+// find returns the first pattern of the leaf whose values are equal and whose
+// range matchers of the residual keys match the request
+func (n *node) find(hash uint64, values []value, sc *scratch, residual []string) *PatternSet {
+	for _, e := range n.leaf(hash) {
+		if valuesEqual(e.values, values) && sc.matchResidual(e.ps, residual) {
+			return e.ps
+		}
+	}
+
+	return nil
+}
+
+// leaf returns the entries of the leaf with the hash, they are ordered like the patterns
+func (n *node) leaf(hash uint64) []entry {
+	for shift := uint(0); n != nil; shift += trieBits {
+		if n.isLeaf() {
+			if n.hash != hash {
+				return nil
+			}
+
+			return n.entries
+		}
+
+		bit := uint32(1) << (hash >> shift & trieMask)
+
+		if n.bitmap&bit == 0 {
+			return nil
+		}
+
+		n = n.children[bits.OnesCount32(n.bitmap&(bit-1))]
+	}
+
+	return nil
+}
+
+// insert returns a copy of the trie which contains e, less orders the patterns of a leaf
+func (n *node) insert(hash uint64, shift uint, e entry, less func(a, b *PatternSet) bool) *node {
+	if n == nil {
+		return &node{hash: hash, entries: []entry{e}}
+	}
+
+	if n.isLeaf() {
+		if n.hash == hash || shift >= 64 {
+			entries := make([]entry, 0, len(n.entries)+1)
+			i := sort.Search(len(n.entries), func(i int) bool { return less(e.ps, n.entries[i].ps) })
+			entries = append(append(append(entries, n.entries[:i]...), e), n.entries[i:]...)
+
+			return &node{hash: n.hash, entries: entries}
+		}
+
+		// split the leaf
+		bit := uint32(1) << (n.hash >> shift & trieMask)
+		parent := &node{bitmap: bit, children: []*node{n}}
+
+		return parent.insert(hash, shift, e, less)
+	}
+
+	bit := uint32(1) << (hash >> shift & trieMask)
+	pos := bits.OnesCount32(n.bitmap & (bit - 1))
+	c := &node{bitmap: n.bitmap | bit}
+
+	if n.bitmap&bit != 0 {
+		c.children = append([]*node{}, n.children...)
+		c.children[pos] = n.children[pos].insert(hash, shift+trieBits, e, less)
+	} else {
+		c.children = make([]*node, 0, len(n.children)+1)
+		c.children = append(append(append(c.children, n.children[:pos]...), &node{hash: hash, entries: []entry{e}}), n.children[pos:]...)
+	}
+
+	return c
+}
+
+// remove returns a copy of the trie without the entries of the patterns for which
+// drop returns true and the number of removed entries
+func (n *node) remove(hash uint64, shift uint, drop func(entry) bool) (*node, int) {
+	if n == nil {
+		return nil, 0
+	}
+
+	if n.isLeaf() {
+		if n.hash != hash {
+			return n, 0
+		}
+
+		entries := []entry{}
+
+		for _, e := range n.entries {
+			if !drop(e) {
+				entries = append(entries, e)
+			}
+		}
+
+		removed := len(n.entries) - len(entries)
+
+		if removed == 0 {
+			return n, 0
+		}
+
+		if len(entries) == 0 {
+			return nil, removed
+		}
+
+		return &node{hash: n.hash, entries: entries}, removed
+	}
+
+	bit := uint32(1) << (hash >> shift & trieMask)
+
+	if n.bitmap&bit == 0 {
+		return n, 0
+	}
+
+	pos := bits.OnesCount32(n.bitmap & (bit - 1))
+	child, removed := n.children[pos].remove(hash, shift+trieBits, drop)
+
+	if removed == 0 {
+		return n, 0
+	}
+
+	c := &node{bitmap: n.bitmap}
+
+	if child != nil {
+		c.children = append([]*node{}, n.children...)
+		c.children[pos] = child
+	} else {
+		c.bitmap &^= bit
+
+		if c.bitmap == 0 {
+			return nil, removed
+		}
+
+		c.children = make([]*node, 0, len(n.children)-1)
+		c.children = append(append(c.children, n.children[:pos]...), n.children[pos+1:]...)
+	}
+
+	return c, removed
+}
+
+// each calls fn for every entry of the trie
+func (n *node) each(fn func(entry)) {
+	if n == nil {
+		return
+	}
+
+	for _, e := range n.entries {
+		fn(e)
+	}
+
+	for _, c := range n.children {
+		c.each(fn)
+	}
+}
+
+// layout returns the keys by which the pattern is indexed, the keys of its range
+// matchers and the values of the keys for every combination of set members
+func (ps *PatternSet) layout() ([]string, []string, [][]value) {
+	keys := append([]string{}, ps.keys...)
+	residual := []string{}
+	sets := make(map[string][]value)
+
+	for key, m := range ps.Matchers {
+		if s, ok := m.(Set); ok {
+			keys = append(keys, key)
+			sets[key] = s.members()
+		} else {
+			residual = append(residual, key)
+		}
+	}
+
+	sort.Strings(keys)
+	sort.Strings(residual)
+
+	entries := [][]value{make([]value, 0, len(keys))}
+
+	for _, key := range keys {
+		members, ok := sets[key]
+
+		if !ok {
+			members = ps.values[sort.SearchStrings(ps.keys, key):][:1]
+		}
+
+		expanded := make([][]value, 0, len(entries)*len(members))
+
+		for _, values := range entries {
+			for _, m := range members {
+				expanded = append(expanded, append(append(make([]value, 0, len(keys)), values...), m))
+			}
+		}
+
+		entries = expanded
+	}
+
+	return keys, residual, entries
+}
+
+// wildcard check if the pattern has matchers which can't be indexed
+func (ps *PatternSet) wildcard() bool {
+	for _, m := range ps.Matchers {
+		if _, ok := m.(valueMatcher); !ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// list returns all patterns of the snapshot by insertion order
+func (idx *index) list() PatternSets {
+	list := make(PatternSets, 0, idx.size)
+
+	for _, g := range idx.groups {
+		g.root.each(func(e entry) {
+			if e.primary {
+				list = append(list, e.ps)
+			}
+		})
+	}
+
+	list = append(list, idx.wildcards...)
+
+	sort.Slice(list, func(i int, j int) bool {
+		return list[i].order < list[j].order
+	})
+
+	return list
+}
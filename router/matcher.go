@@ -0,0 +1,221 @@
+package router
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Matcher is a pattern value which matches a set of values instead of a single one.
+// Patterns with matchers compete with exact patterns by the indexing strategy.
+type Matcher interface {
+	Match(value interface{}) bool
+}
+
+type anyValue struct{}
+
+// Any matches every value which is present in the request. It can be assigned to interface{} fields.
+var Any Matcher = anyValue{}
+
+func (anyValue) Match(value interface{}) bool {
+	return true
+}
+
+func (anyValue) String() string {
+	return "*"
+}
+
+type zeroValue struct{}
+
+// Zero matches absent and zero values. It is registered for zero fields with the `hemera:"match"` tag.
+var Zero Matcher = zeroValue{}
+
+func (zeroValue) Match(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+
+	val, ok := valueOf(v)
+	return ok && zeroValue{}.matchValue(val)
+}
+
+func (zeroValue) matchValue(v value) bool {
+	return v.n == 0 && v.s == ""
+}
+
+func (zeroValue) String() string {
+	return "zero"
+}
+
+// Glob matches strings against a pattern in which `*` stands for any sequence of characters.
+// It can be assigned to interface{} fields, plain string values are always compared literally.
+type Glob string
+
+func (g Glob) Match(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && globMatch(string(g), s)
+}
+
+func globMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+
+	if len(parts) == 1 {
+		return pattern == s
+	}
+
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+
+	s = s[len(parts[0]):]
+
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+
+		if i < 0 {
+			return false
+		}
+
+		s = s[i+len(part):]
+	}
+
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
+
+// Regexp matches string values against a regular expression.
+// Its exact fields are compared first, the expression is only evaluated for remaining candidates.
+type Regexp struct {
+	re *regexp.Regexp
+}
+
+// NewRegexp compile the expression into a Regexp matcher
+func NewRegexp(expr string) (Regexp, error) {
+	re, err := regexp.Compile(expr)
+
+	if err != nil {
+		return Regexp{}, err
+	}
+
+	return Regexp{re: re}, nil
+}
+
+// MustRegexp is like NewRegexp but panics if the expression cannot be parsed
+func MustRegexp(expr string) Regexp {
+	r, err := NewRegexp(expr)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+func (r Regexp) Match(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && r.re != nil && r.re.MatchString(s)
+}
+
+func (r Regexp) String() string {
+	if r.re == nil {
+		return ""
+	}
+
+	return r.re.String()
+}
+
+// valueMatcher is implemented by matchers of indexed patterns, they are checked
+// after the exact fields without boxing the request value
+type valueMatcher interface {
+	Matcher
+	matchValue(v value) bool
+}
+
+// matchAbsent check if m matches a field which is not part of the request
+func matchAbsent(m Matcher) bool {
+	vm, ok := m.(valueMatcher)
+	return ok && vm.matchValue(value{})
+}
+
+// Set matches every value which is equal to one of its members. Patterns with sets
+// are indexed under each member like an exact value.
+type Set []interface{}
+
+// In create a Set of the values
+func In(values ...interface{}) Set {
+	return Set(values)
+}
+
+func (s Set) Match(v interface{}) bool {
+	val, ok := valueOf(v)
+	return ok && s.matchValue(val)
+}
+
+func (s Set) matchValue(v value) bool {
+	for _, m := range s.members() {
+		if m == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// members returns the distinct normalized values of the set, members like 1 and 1.0
+// are equal after the normalization and would index the pattern twice
+func (s Set) members() []value {
+	members := make([]value, 0, len(s))
+	seen := make(map[value]bool, len(s))
+
+	for _, m := range s {
+		if v, ok := valueOf(m); ok && !seen[v] {
+			seen[v] = true
+			members = append(members, v)
+		}
+	}
+
+	return members
+}
+
+// Range matches numbers from Min inclusive to Max exclusive.
+// Patterns with ranges are indexed by their exact fields, the range is checked afterwards.
+type Range struct {
+	Min float64
+	Max float64
+}
+
+// Between create a Range from min inclusive to max exclusive
+func Between(min, max float64) Range {
+	return Range{Min: min, Max: max}
+}
+
+// AtLeast create a Range which has no upper bound
+func AtLeast(min float64) Range {
+	return Range{Min: min, Max: math.Inf(1)}
+}
+
+func (r Range) Match(v interface{}) bool {
+	val, ok := valueOf(v)
+	return ok && r.matchValue(val)
+}
+
+func (r Range) matchValue(v value) bool {
+	var f float64
+
+	switch v.kind {
+	case kindInt:
+		f = float64(int64(v.n))
+	case kindUint:
+		f = float64(v.n)
+	case kindFloat:
+		f = math.Float64frombits(v.n)
+	default:
+		return false
+	}
+
+	return f >= r.Min && f < r.Max
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("[%v, %v)", r.Min, r.Max)
+}
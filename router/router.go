@@ -1,140 +1,324 @@
 package router
 
 import (
-	"math"
-	"reflect"
+	"encoding/json"
+	"fmt"
 	"sort"
-	"strings"
-
-	"github.com/emirpasic/gods/maps/hashmap"
-	"github.com/emirpasic/gods/sets/hashset"
-	"github.com/fatih/structs"
+	"sync"
+	"sync/atomic"
 )
 
 type PatternFieldValue interface{}
 type PatternFields map[string]PatternFieldValue
 
 type PatternSet struct {
-	Pattern interface{}
-	Weight  int
-	Fields  PatternFields
-	Payload interface{}
+	Pattern  interface{}
+	Weight   int
+	Priority int
+	Fields   PatternFields
+	Matchers PatternMatchers
+	Payload  interface{}
+	order    int
+	// sorted keys of the exact fields and their values
+	keys   []string
+	values []value
 }
 
-type PatternSets []*PatternSet
+type PatternMatchers map[string]Matcher
 
-type Bucket struct {
-	PatternSets PatternSets
-	Weight      int
-}
+type PatternSets []*PatternSet
 
+// Router is safe for concurrent use. Writers copy the parts of the index they
+// modify and publish a new snapshot, a Lookup never waits for a lock.
 type Router struct {
-	Map         *hashmap.Map
-	Buckets     []*Bucket
 	IsDeep      bool
+	mu          sync.Mutex
+	snapshot    atomic.Value
 	insertCount int
 }
 
-//NewRouter creaet a new router
+// scratch holds the fields of a request sorted by key during a lookup
+type scratch struct {
+	fields []scratchField
+	buf    []value
+}
+
+type scratchField struct {
+	key string
+	val value
+}
+
+var scratchPool = sync.Pool{
+	New: func() interface{} {
+		return &scratch{fields: make([]scratchField, 0, 16), buf: make([]value, 0, 16)}
+	},
+}
+
+// NewRouter creaet a new router
 func NewRouter(IsDeep bool) *Router {
-	hm := hashmap.New()
+	r := &Router{IsDeep: IsDeep}
+	r.snapshot.Store(&index{byKeys: make(map[string]*group)})
+
+	return r
+}
 
-	return &Router{Map: hm, IsDeep: IsDeep}
+// emptyIndex is the snapshot of a router which was declared without NewRouter
+var emptyIndex = &index{byKeys: make(map[string]*group)}
+
+func (r *Router) load() *index {
+	if idx, ok := r.snapshot.Load().(*index); ok {
+		return idx
+	}
+
+	return emptyIndex
 }
 
 // Add Insert a new pattern
 func (r *Router) Add(pattern, payload interface{}) {
-	ps := r.convertToPatternSet(pattern)
+	r.AddWithPriority(pattern, payload, 0)
+}
+
+// AddWithPriority Insert a new pattern which wins over all patterns with a lower priority.
+// The priority overrides the indexing strategy.
+func (r *Router) AddWithPriority(pattern, payload interface{}, priority int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.add(pattern, payload, priority)
+}
+
+// AddChecked inserts the pattern when check accepts its conflicts. The check and the insert
+// are atomic, concurrent adds of the same pattern can't both pass the check.
+func (r *Router) AddChecked(pattern, payload interface{}, priority int, check func([]Conflict) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := check(r.ConflictsWithPriority(pattern, priority)); err != nil {
+		return err
+	}
+
+	r.add(pattern, payload, priority)
+
+	return nil
+}
+
+// add inserts the pattern, the caller holds the lock
+func (r *Router) add(pattern, payload interface{}, priority int) {
+	ps := r.convertToPatternSet(pattern, true)
 	ps.Payload = payload
+	ps.Priority = priority
+
+	r.insertCount++
+	ps.order = r.insertCount
+
+	// sort by insertion order
+	if !r.IsDeep {
+		ps.Weight = r.insertCount
+	}
+
+	old := r.load()
+	idx := &index{groups: old.groups, byKeys: old.byKeys, wildcards: old.wildcards, size: old.size + 1}
+
+	// patterns with matchers which can't be indexed are scanned
+	// when no indexed pattern matched
+	if ps.wildcard() {
+		idx.wildcards = append(append(PatternSets{}, old.wildcards...), ps)
+		r.sortPatternSets(idx.wildcards)
+		r.snapshot.Store(idx)
+		return
+	}
 
-	for key, val := range ps.Fields {
-		// create map to save key -> value pair
-		if _, ok := r.Map.Get(key); !ok {
-			r.Map.Put(key, hashmap.New())
+	keys, residual, entries := ps.layout()
+	id := groupID(keys, residual)
+	g := &group{keys: keys, residual: residual, weight: len(keys) + len(residual), first: ps.order, priority: ps.Priority}
+
+	if o, ok := old.byKeys[id]; ok {
+		*g = *o
+
+		if g.priority < ps.Priority {
+			g.priority = ps.Priority
 		}
+	}
 
-		patternField, _ := r.Map.Get(key)
-		patternValueMap := patternField.(*hashmap.Map)
-		patternValueMapValue, ok := patternValueMap.Get(val)
+	for i, values := range entries {
+		g.root = g.root.insert(hashValues(values), 0, entry{values: values, ps: ps, primary: i == 0}, r.before)
+		g.size++
+	}
 
-		var bucket *Bucket
+	idx.byKeys = make(map[string]*group, len(old.byKeys)+1)
 
-		if !ok {
+	for k, o := range old.byKeys {
+		idx.byKeys[k] = o
+	}
+
+	idx.byKeys[id] = g
+	idx.groups = r.sortGroups(idx.byKeys)
+
+	r.snapshot.Store(idx)
+}
+
+// Remove deletes all patterns which have the same fields and matchers as the pattern
+// and returns true when a pattern was removed
+func (r *Router) Remove(pattern interface{}) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ps := r.convertToPatternSet(pattern, true)
+	old := r.load()
+	idx := &index{groups: old.groups, byKeys: old.byKeys, wildcards: old.wildcards, size: old.size}
+	removed := 0
 
-			// fmt.Printf("Create bucket Key: '%+v' Value: '%+v'\n", key, val)
-			bucket = &Bucket{}
+	if ps.wildcard() {
+		idx.wildcards = PatternSets{}
 
-			if r.IsDeep {
-				bucket.Weight = 0
+		for _, p := range old.wildcards {
+			if samePattern(p, ps) {
+				removed++
 			} else {
-				bucket.Weight = math.MaxInt32
+				idx.wildcards = append(idx.wildcards, p)
 			}
+		}
+	} else {
+		keys, residual, entries := ps.layout()
+		id := groupID(keys, residual)
+		o, ok := old.byKeys[id]
+
+		if !ok {
+			return false
+		}
 
-			patternValueMap.Put(val, bucket)
+		g := *o
 
-			r.Buckets = append(r.Buckets, bucket)
-		} else {
-			bucket = patternValueMapValue.(*Bucket)
+		for _, values := range entries {
+			var n int
+
+			g.root, n = g.root.remove(hashValues(values), 0, func(e entry) bool {
+				if !samePattern(e.ps, ps) {
+					return false
+				}
+
+				if e.primary {
+					removed++
+				}
+
+				return true
+			})
+			g.size -= n
 		}
 
-		if r.IsDeep {
-			if bucket.Weight < ps.Weight {
-				bucket.Weight = ps.Weight
-			}
-		} else {
-			if bucket.Weight > ps.Weight {
-				bucket.Weight = ps.Weight
-			}
+		if removed == 0 {
+			return false
 		}
 
-		// pattern to bucket
-		bucket.PatternSets = append(bucket.PatternSets, ps)
+		idx.byKeys = make(map[string]*group, len(old.byKeys))
 
-		//sort buckets of pattern
-		if r.IsDeep {
-			sort.Slice(bucket.PatternSets, func(i int, j int) bool {
-				return bucket.PatternSets[i].Weight > bucket.PatternSets[j].Weight
-			})
+		for k, o := range old.byKeys {
+			idx.byKeys[k] = o
+		}
+
+		if g.size == 0 {
+			delete(idx.byKeys, id)
 		} else {
-			sort.Slice(bucket.PatternSets, func(i int, j int) bool {
-				return bucket.PatternSets[i].Weight < bucket.PatternSets[j].Weight
-			})
+			idx.byKeys[id] = &g
 		}
+
+		idx.groups = r.sortGroups(idx.byKeys)
+	}
+
+	if removed == 0 {
+		return false
 	}
 
+	idx.size -= removed
+	r.snapshot.Store(idx)
+
+	return true
 }
 
-func (r *Router) List() PatternSets {
-	list := PatternSets{}
-	visited := hashset.New()
+// samePattern check if both patterns have equal fields and matchers
+func samePattern(a *PatternSet, b *PatternSet) bool {
+	if len(a.Fields) != len(b.Fields) || len(a.Matchers) != len(b.Matchers) {
+		return false
+	}
 
-	for _, key := range r.Map.Keys() {
+	if !FieldsArrayEquals(a.Fields, b.Fields) {
+		return false
+	}
 
-		val, _ := r.Map.Get(key)
-		pv := val.(*hashmap.Map)
+	for key, m := range b.Matchers {
+		o, ok := a.Matchers[key]
 
-		for _, o := range pv.Keys() {
+		if !ok || fmt.Sprintf("%T:%v", o, o) != fmt.Sprintf("%T:%v", m, m) {
+			return false
+		}
+	}
 
-			b, ok := pv.Get(o)
+	return true
+}
 
-			if ok {
-				ps := b.(*Bucket)
+// before check if a wins over b by priority and the indexing strategy, equal weights by specificity and insertion order
+func (r *Router) before(a, b *PatternSet) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
 
-				for _, p := range ps.PatternSets {
+	if r.IsDeep && a.Weight != b.Weight {
+		return a.Weight > b.Weight
+	}
 
-					if !visited.Contains(p) {
-						visited.Add(p)
-						list = append(list, p)
-					}
+	// exact values are more specific than sets and ranges
+	if r.IsDeep && len(a.Matchers) != len(b.Matchers) {
+		return len(a.Matchers) < len(b.Matchers)
+	}
 
-				}
-			}
+	return a.order < b.order
+}
 
+// sortPatternSets sort the patterns by the indexing strategy
+func (r *Router) sortPatternSets(sets PatternSets) {
+	sort.SliceStable(sets, func(i int, j int) bool {
+		return r.before(sets[i], sets[j])
+	})
+}
+
+// sortGroups order the groups so that a lookup can stop at the first group
+// which can't contain a better match
+func (r *Router) sortGroups(byKeys map[string]*group) []*group {
+	groups := make([]*group, 0, len(byKeys))
+
+	for _, g := range byKeys {
+		groups = append(groups, g)
+	}
+
+	sort.Slice(groups, func(i int, j int) bool {
+		if groups[i].priority != groups[j].priority {
+			return groups[i].priority > groups[j].priority
+		}
+		if r.IsDeep && groups[i].weight != groups[j].weight {
+			return groups[i].weight > groups[j].weight
 		}
+		return groups[i].first < groups[j].first
+	})
 
+	return groups
+}
+
+// skip check if no pattern of the group can win over best
+func (r *Router) skip(g *group, best *PatternSet) bool {
+	if g.priority != best.Priority {
+		return g.priority < best.Priority
 	}
 
-	return list
+	if r.IsDeep {
+		return g.weight < best.Weight
+	}
+
+	return g.first > best.order
+}
+
+// List returns all patterns by insertion order
+func (r *Router) List() PatternSets {
+	return r.load().list()
 }
 
 // FieldsArrayEquals check if b is subset of a
@@ -148,124 +332,262 @@ func FieldsArrayEquals(a PatternFields, b PatternFields) bool {
 	return true
 }
 
-// equals is shorthand for FieldsArrayEquals
-func equals(a *PatternSet, b *PatternSet) bool {
-	return FieldsArrayEquals(a.Fields, b.Fields)
+// FieldsMatch check if the fields satisfy the exact values and matchers of p
+func FieldsMatch(fields PatternFields, p *PatternSet) bool {
+	if !FieldsArrayEquals(fields, p.Fields) {
+		return false
+	}
+
+	for key, m := range p.Matchers {
+		val, ok := fields[key]
+
+		if !ok && !matchAbsent(m) || ok && !m.Match(val) {
+			return false
+		}
+	}
+
+	return true
 }
 
 // Lookup Search for a specific pattern and returns it
 func (r *Router) Lookup(p interface{}) *PatternSet {
+	idx := r.load()
+	sc := r.scratch(p)
+	defer scratchPool.Put(sc)
 
-	ps := r.convertToPatternSet(p)
+	var best *PatternSet
 
-	buckets := []*Bucket{}
+	for _, g := range idx.groups {
+		if best != nil && r.skip(g, best) {
+			break
+		}
 
-	for key, val := range ps.Fields {
+		if h, ok := sc.hash(g.keys); ok {
+			if ps := g.root.find(h, sc.buf, sc, g.residual); ps != nil && (best == nil || r.before(ps, best)) {
+				best = ps
+			}
+		}
+	}
 
-		// return e.g value of "topic"
-		patternField, ok := r.Map.Get(key)
+	// wildcard patterns are ordered like indexed patterns, the scan stops
+	// at the first one which can't win over the best indexed match
+	for _, pattern := range idx.wildcards {
+		if best != nil && !r.before(pattern, best) {
+			break
+		}
 
-		// when key was not indexed
-		if !ok {
-			continue
+		if sc.match(pattern) {
+			return pattern
 		}
+	}
 
-		// convert value to hashMap
-		patternValueMap := patternField.(*hashmap.Map)
+	return best
+}
 
-		// return bucket of e.g "topic" -> "math" -> bucket
-		patternValueMapValue, ok := patternValueMap.Get(val)
+// LookupAll returns every pattern which matches p ordered by priority and the indexing strategy
+func (r *Router) LookupAll(p interface{}) PatternSets {
+	idx := r.load()
+	sc := r.scratch(p)
+	defer scratchPool.Put(sc)
 
-		if ok {
-			b, ok := patternValueMapValue.(*Bucket)
+	list := PatternSets{}
 
-			if !ok {
-				panic("Value is not from type *Bucket")
+	for _, g := range idx.groups {
+		if h, ok := sc.hash(g.keys); ok {
+			for _, e := range g.root.leaf(h) {
+				if valuesEqual(e.values, sc.buf) && sc.matchResidual(e.ps, g.residual) {
+					list = append(list, e.ps)
+				}
 			}
+		}
+	}
+
+	for _, pattern := range idx.wildcards {
+		if sc.match(pattern) {
+			list = append(list, pattern)
+		}
+	}
 
-			buckets = append(buckets, b)
+	r.sortPatternSets(list)
 
-			//sort buckets
-			if r.IsDeep {
-				sort.Slice(buckets, func(i int, j int) bool {
-					return buckets[i].Weight > buckets[j].Weight
-				})
-			} else {
-				sort.Slice(buckets, func(i int, j int) bool {
-					return buckets[i].Weight < buckets[j].Weight
-				})
-			}
+	return list
+}
+
+// scratch collects the fields of a request into a pooled scratch
+func (r *Router) scratch(p interface{}) *scratch {
+	sc := scratchPool.Get().(*scratch)
+	sc.fields = sc.fields[:0]
 
+	walkPattern(sc, p)
+
+	// insertion sort, requests have only a few fields
+	f := sc.fields
+
+	for i := 1; i < len(f); i++ {
+		for j := i; j > 0 && f[j].key < f[j-1].key; j-- {
+			f[j], f[j-1] = f[j-1], f[j]
 		}
 	}
 
-	/* for _, x := range buckets {
-		fmt.Printf("Bucket %+v\n", x)
-		for _, p := range x.PatternSets {
-			fmt.Printf("		Set: %+v\n", p)
+	// keys which only differ in case are reduced to one field
+	n := 0
+
+	for i := range f {
+		if n > 0 && f[n-1].key == f[i].key {
+			f[n-1] = f[i]
+		} else {
+			f[n] = f[i]
+			n++
 		}
+	}
 
-	} */
+	sc.fields = f[:n]
 
-	var matched bool
+	return sc
+}
 
-	for _, bucket := range buckets {
-		for _, pattern := range bucket.PatternSets {
+func (sc *scratch) field(key string, v value) {
+	sc.fields = append(sc.fields, scratchField{key: key, val: v})
+}
 
-			matched = equals(ps, pattern)
+// matchers of a request are compared literally, they are never routed
+func (sc *scratch) matcher(key string, m Matcher) {}
 
-			if matched {
-				return pattern
-			}
+// get returns the value of the key
+func (sc *scratch) get(key string) (value, bool) {
+	i := sort.Search(len(sc.fields), func(i int) bool { return sc.fields[i].key >= key })
 
+	if i < len(sc.fields) && sc.fields[i].key == key {
+		return sc.fields[i].val, true
+	}
+
+	return value{}, false
+}
+
+// hash returns the hash of the request values of the sorted keys and keeps the values in buf
+func (sc *scratch) hash(keys []string) (uint64, bool) {
+	sc.buf = sc.buf[:0]
+
+	if len(keys) > len(sc.fields) {
+		return 0, false
+	}
+
+	h := uint64(offset64)
+	i := 0
+
+	for _, key := range keys {
+		for i < len(sc.fields) && sc.fields[i].key < key {
+			i++
+		}
+
+		if i == len(sc.fields) || sc.fields[i].key != key {
+			return 0, false
 		}
+
+		v := sc.fields[i].val
+		sc.buf = append(sc.buf, v)
+		h = v.hash(h)
 	}
 
-	return nil
+	return h, true
+}
+
+// match check if the request satisfies the exact values and matchers of p
+func (sc *scratch) match(p *PatternSet) bool {
+	if _, ok := sc.hash(p.keys); !ok || !valuesEqual(sc.buf, p.values) {
+		return false
+	}
+
+	for key, m := range p.Matchers {
+		v, ok := sc.get(key)
+
+		if !ok && !matchAbsent(m) || ok && !m.Match(v.iface()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchResidual check if the range and zero matchers of the keys match the request
+func (sc *scratch) matchResidual(p *PatternSet, keys []string) bool {
+	for _, key := range keys {
+		// absent fields are compared as zero values
+		v, _ := sc.get(key)
+
+		if !p.Matchers[key].(valueMatcher).matchValue(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// walkPattern walk the fields of a struct, a map or a JSON object.
+// Invalid JSON documents have no fields.
+func walkPattern(s sink, p interface{}) {
+	var data []byte
+
+	switch v := p.(type) {
+	case []byte:
+		data = v
+	case json.RawMessage:
+		data = v
+	default:
+		walk(s, "", p)
+		return
+	}
+
+	m := make(map[string]interface{})
+
+	if err := jsonNumber.Unmarshal(data, &m); err == nil {
+		walk(s, "", m)
+	}
+}
 
+// patternSink collects the fields of a pattern into a patternset.
+// Matchers are only created for registered patterns, a lookup compares their values literally.
+type patternSink struct {
+	ps       *PatternSet
+	register bool
+	values   map[string]value
 }
 
-// convertToPatternSet convert a struct to a patternset
-func (r *Router) convertToPatternSet(p interface{}) *PatternSet {
-	fields := structs.Fields(p)
+func (s *patternSink) field(key string, v value) {
+	s.ps.Fields[key] = v.iface()
+	s.ps.Weight++
+	s.values[key] = v
+}
 
+func (s *patternSink) matcher(key string, m Matcher) {
+	if s.register {
+		s.ps.Matchers[key] = m
+		s.ps.Weight++
+	}
+}
+
+// convertToPatternSet convert a struct, a map or a JSON object to a patternset
+func (r *Router) convertToPatternSet(p interface{}, register bool) *PatternSet {
 	ps := &PatternSet{}
 	ps.Fields = make(PatternFields)
+	ps.Matchers = make(PatternMatchers)
 	ps.Pattern = p
 	ps.Weight = 0
 
-	for _, field := range fields {
-		if !strings.HasSuffix(field.Name(), "_") && !field.IsZero() {
-			fieldKind := field.Kind()
-
-			switch fieldKind {
-			case reflect.Int8:
-				fallthrough
-			case reflect.Int16:
-				fallthrough
-			case reflect.Int32:
-				fallthrough
-			case reflect.Int64:
-				fallthrough
-			case reflect.Int:
-				fallthrough
-			case reflect.String:
-				fallthrough
-			case reflect.Bool:
-				fallthrough
-			case reflect.Float32:
-				fallthrough
-			case reflect.Float64:
-				ps.Fields[field.Name()] = field.Value()
-				ps.Weight++
-			}
-		}
+	s := &patternSink{ps: ps, register: register, values: make(map[string]value)}
+	walkPattern(s, p)
+
+	ps.keys = make([]string, 0, len(s.values))
+
+	for key := range s.values {
+		ps.keys = append(ps.keys, key)
 	}
 
-	// sort by insertion order
-	if !r.IsDeep {
-		r.insertCount++
-		ps.Weight = r.insertCount
+	sort.Strings(ps.keys)
+	ps.values = make([]value, len(ps.keys))
+
+	for i, key := range ps.keys {
+		ps.values[i] = s.values[key]
 	}
 
 	return ps
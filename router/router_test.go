@@ -1,6 +1,10 @@
 package router
 
 import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -30,6 +34,12 @@ type DynPattern struct {
 	J     string
 }
 
+type GlobPattern struct {
+	Topic string
+	Cmd   interface{}
+	A     string
+}
+
 type TestIntPattern struct {
 	Topic string
 	Cmd   string
@@ -191,6 +201,321 @@ func TestUnMatchedLookupWhenTreeEmptyDepth(t *testing.T) {
 
 }
 
+type AnyPattern struct {
+	Topic string
+	Cmd   interface{}
+	A     interface{}
+}
+
+func TestWildcardLookup(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(GlobPattern{Topic: "order", Cmd: Glob("order.*")}, "glob")
+	hr.Add(AnyPattern{Topic: "user", Cmd: Any}, "any")
+
+	p := hr.Lookup(DynPattern{Topic: "order", Cmd: "order.create"})
+	assert.Equal(p.Payload, "glob", "Should be `glob`")
+
+	p = hr.Lookup(DynPattern{Topic: "order", Cmd: "invoice.create"})
+	assert.Empty(p, "Pattern not found", "Should pattern not found")
+
+	p = hr.Lookup(DynPattern{Topic: "user", Cmd: "delete"})
+	assert.Equal(p.Payload, "any", "Should be `any`")
+
+	p = hr.Lookup(DynPattern{Topic: "user"})
+	assert.Empty(p, "Pattern not found", "Any should require a present value")
+}
+
+func TestWildcardPrecedenceDepth(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(GlobPattern{Topic: "order", Cmd: Glob("order.*"), A: "1"}, "glob")
+	hr.Add(DynPattern{Topic: "order"}, "exact")
+	hr.Add(GlobPattern{Topic: "order", Cmd: Glob("*")}, "any")
+
+	p := hr.Lookup(DynPattern{Topic: "order", Cmd: "order.create", A: "1"})
+	assert.Equal(p.Payload, "glob", "Should prefer the deeper wildcard over the exact pattern")
+
+	p = hr.Lookup(DynPattern{Topic: "order"})
+	assert.Equal(p.Payload, "exact", "Should be `exact`")
+
+	hr.Add(DynPattern{Topic: "order", Cmd: "order.create", A: "1"}, "deep")
+
+	p = hr.Lookup(DynPattern{Topic: "order", Cmd: "order.create", A: "1"})
+	assert.Equal(p.Payload, "deep", "Should prefer the exact pattern of equal weight")
+
+	hr = NewRouter(true)
+	hr.Add(GlobPattern{Topic: "order", Cmd: Glob("*")}, "any")
+	hr.Add(GlobPattern{Topic: "order", Cmd: Glob("order.*"), A: "1"}, "glob")
+
+	p = hr.Lookup(DynPattern{Topic: "order", Cmd: "order.create", A: "1"})
+	assert.Equal(p.Payload, "glob", "Should prefer the deeper wildcard")
+}
+
+func TestWildcardPrecedenceInsertion(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(false)
+	hr.Add(DynPattern{Topic: "order", Cmd: "order.create"}, "exact")
+	hr.Add(GlobPattern{Topic: "order", Cmd: Glob("*")}, "any")
+	hr.Add(GlobPattern{Topic: "order", Cmd: Glob("order.*")}, "glob")
+	hr.Add(DynPattern{Topic: "order", Cmd: "order.update"}, "late")
+
+	p := hr.Lookup(DynPattern{Topic: "order", Cmd: "order.create"})
+	assert.Equal(p.Payload, "exact", "Should prefer the first pattern")
+
+	p = hr.Lookup(DynPattern{Topic: "order", Cmd: "order.delete"})
+	assert.Equal(p.Payload, "any", "Should prefer the first wildcard")
+
+	p = hr.Lookup(DynPattern{Topic: "order", Cmd: "order.update"})
+	assert.Equal(p.Payload, "any", "Should prefer the earlier wildcard over a later exact pattern")
+}
+
+func TestGlobMatch(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(Glob("order.*").Match("order.create"))
+	assert.True(Glob("*.create").Match("order.create"))
+	assert.True(Glob("o*r.*e").Match("order.create"))
+	assert.True(Glob("*").Match(""))
+	assert.False(Glob("order.*").Match("orders"))
+	assert.False(Glob("*.create").Match(1))
+}
+
+func TestLiteralAsterisk(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(DynPattern{Topic: "files", Cmd: "a*b"}, "literal")
+
+	assert.Nil(hr.Lookup(DynPattern{Topic: "files", Cmd: "axxb"}), "Should not be a glob")
+
+	p := hr.Lookup(DynPattern{Topic: "files", Cmd: "a*b"})
+	assert.Equal(p.Payload, "literal", "Should compare the value literally")
+}
+
+type RegexpPattern struct {
+	Topic string
+	Cmd   Regexp
+}
+
+func TestRegexpLookup(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(RegexpPattern{Topic: "order", Cmd: MustRegexp("^(create|update)$")}, "regexp")
+	hr.Add(AnyPattern{Topic: "order", Cmd: MustRegexp("^delete"), A: "1"}, "regexp2")
+	hr.Add(DynPattern{Topic: "order", Cmd: "update"}, "exact")
+
+	p := hr.Lookup(DynPattern{Topic: "order", Cmd: "create"})
+	assert.Equal(p.Payload, "regexp", "Should be `regexp`")
+
+	p = hr.Lookup(DynPattern{Topic: "order", Cmd: "update"})
+	assert.Equal(p.Payload, "exact", "Should prefer exact patterns")
+
+	p = hr.Lookup(DynPattern{Topic: "order", Cmd: "deleteAll", A: "1"})
+	assert.Equal(p.Payload, "regexp2", "Should be `regexp2`")
+
+	p = hr.Lookup(DynPattern{Topic: "order", Cmd: "remove"})
+	assert.Empty(p, "Pattern not found", "Should pattern not found")
+
+	_, err := NewRegexp("(")
+	assert.Error(err, "Should reject invalid expressions")
+}
+
+type Filter struct {
+	Role   string
+	Active bool
+}
+
+type NestedPattern struct {
+	Topic  string
+	Filter Filter
+}
+
+type NestedPtrPattern struct {
+	Topic  string
+	Filter *Filter
+	Labels map[string]interface{}
+}
+
+func TestNestedLookup(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(NestedPattern{Topic: "user"}, "user")
+	hr.Add(NestedPattern{Topic: "user", Filter: Filter{Role: "admin"}}, "admin")
+	hr.Add(NestedPtrPattern{Topic: "user", Labels: map[string]interface{}{"team": "core"}}, "core")
+
+	p := hr.Lookup(NestedPattern{Topic: "user", Filter: Filter{Role: "admin", Active: true}})
+	assert.Equal(p.Payload, "admin", "Should be `admin`")
+
+	p = hr.Lookup(NestedPtrPattern{Topic: "user", Filter: &Filter{Role: "admin"}})
+	assert.Equal(p.Payload, "admin", "Should flatten pointers")
+
+	p = hr.Lookup(NestedPattern{Topic: "user", Filter: Filter{Role: "guest"}})
+	assert.Equal(p.Payload, "user", "Should be `user`")
+
+	p = hr.Lookup(NestedPtrPattern{Topic: "user", Labels: map[string]interface{}{"team": "core"}})
+	assert.Equal(p.Payload, "core", "Should flatten maps")
+
+	ps := hr.convertToPatternSet(NestedPtrPattern{Topic: "user", Filter: &Filter{Role: "admin"}, Labels: map[string]interface{}{"team": "core"}}, false)
+	assert.Equal(ps.Fields, PatternFields{"topic": "user", "filter.role": "admin", "labels.team": "core"}, "Should use dotted keys")
+}
+
+func TestMapLookup(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(TestIntPattern{Topic: "math", Cmd: "add", A: 1}, "struct")
+	hr.Add(map[string]interface{}{"topic": "math", "cmd": "sub"}, "map")
+	hr.Add([]byte(`{"topic":"math","cmd":"mul","a":2.5}`), "json")
+
+	p := hr.Lookup(map[string]interface{}{"topic": "math", "cmd": "add", "a": float64(1), "b": float64(2)})
+	assert.Equal(p.Payload, "struct", "Should match JSON numbers with Go ints")
+
+	p = hr.Lookup(map[string]interface{}{"Topic": "math", "Cmd": "sub"})
+	assert.Equal(p.Payload, "map", "Should match keys case-insensitive")
+
+	p = hr.Lookup([]byte(`{"topic":"math","cmd":"add","a":1}`))
+	assert.Equal(p.Payload, "struct", "Should match JSON patterns")
+
+	p = hr.Lookup(struct {
+		Topic string
+		Cmd   string
+		A     float32
+	}{Topic: "math", Cmd: "mul", A: 2.5})
+	assert.Equal(p.Payload, "json", "Should match floats")
+
+	p = hr.Lookup(map[string]interface{}{"topic": "math", "cmd": "add", "a": 1.5})
+	assert.Empty(p, "Pattern not found", "Should pattern not found")
+
+	p = hr.Lookup([]byte(`{invalid`))
+	assert.Empty(p, "Pattern not found", "Should pattern not found")
+
+	p = hr.Lookup(nil)
+	assert.Empty(p, "Pattern not found", "Should pattern not found")
+}
+
+func TestLookupAllDepth(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(DynPattern{Topic: "math"}, "test")
+	hr.Add(GlobPattern{Topic: "math", Cmd: Glob("*")}, "test1")
+	hr.Add(DynPattern{Topic: "math", Cmd: "add", A: "1"}, "test2")
+	hr.Add(DynPattern{Topic: "math", Cmd: "add"}, "test3")
+	hr.Add(DynPattern{Topic: "math", A: "1"}, "test4")
+	hr.Add(DynPattern{Topic: "payment"}, "test5")
+
+	payloads := []interface{}{}
+
+	for _, p := range hr.LookupAll(DynPattern{Topic: "math", Cmd: "add", A: "1"}) {
+		payloads = append(payloads, p.Payload)
+	}
+
+	assert.Equal(payloads, []interface{}{"test2", "test3", "test4", "test1", "test"}, "Should be ordered by depth")
+	assert.Empty(hr.LookupAll(DynPattern{Topic: "order"}), "Should be empty")
+}
+
+func TestLookupAllInsertion(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(false)
+	hr.Add(DynPattern{Topic: "math", Cmd: "add"}, "test")
+	hr.Add(DynPattern{Topic: "math"}, "test1")
+	hr.Add(DynPattern{Topic: "math", Cmd: "sub"}, "test2")
+
+	payloads := []interface{}{}
+
+	for _, p := range hr.LookupAll(DynPattern{Topic: "math", Cmd: "add"}) {
+		payloads = append(payloads, p.Payload)
+	}
+
+	assert.Equal(payloads, []interface{}{"test", "test1"}, "Should be ordered by insertion")
+}
+
+func TestRemove(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(DynPattern{Topic: "math"}, "test")
+	hr.Add(DynPattern{Topic: "math", Cmd: "add"}, "test1")
+	hr.Add(GlobPattern{Topic: "math", Cmd: Glob("*")}, "test2")
+
+	assert.True(hr.Remove(DynPattern{Topic: "math", Cmd: "add"}), "Should remove the pattern")
+	assert.False(hr.Remove(DynPattern{Topic: "math", Cmd: "add"}), "Should be removed already")
+
+	p := hr.Lookup(DynPattern{Topic: "math", Cmd: "add"})
+	assert.Equal(p.Payload, "test2", "Should be `test2`")
+
+	p = hr.Lookup(DynPattern{Topic: "math"})
+	assert.Equal(p.Payload, "test", "Should be `test`")
+
+	assert.True(hr.Remove(DynPattern{Topic: "math"}), "Should remove the pattern")
+
+	p = hr.Lookup(DynPattern{Topic: "math", Cmd: "add"})
+	assert.Equal(p.Payload, "test2", "Should be `test2`")
+
+	assert.True(hr.Remove(GlobPattern{Topic: "math", Cmd: Glob("*")}), "Should remove wildcard patterns")
+	assert.Equal(len(hr.List()), 0, "Should be empty")
+}
+
+func TestSnapshotIsolation(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(DynPattern{Topic: "math"}, "test")
+
+	before := hr.load()
+	hr.Add(DynPattern{Topic: "math", Cmd: "add"}, "test1")
+
+	assert.Equal(len(before.list()), 1, "Should not modify published snapshots")
+	assert.Equal(len(hr.load().list()), 2, "Should publish a new snapshot")
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(DynPattern{Topic: "math"}, "test")
+
+	wg := sync.WaitGroup{}
+
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for n := 0; n < 200; n++ {
+				p := DynPattern{Topic: "math", Cmd: fmt.Sprintf("cmd%d-%d", w, n)}
+				hr.Add(p, "test1")
+
+				if n%2 == 0 {
+					hr.Remove(p)
+				}
+			}
+		}(w)
+
+		go func() {
+			defer wg.Done()
+
+			for n := 0; n < 200; n++ {
+				p := hr.Lookup(DynPattern{Topic: "math", Cmd: "unknown"})
+				assert.Equal(p.Payload, "test", "Should be `test`")
+				hr.List()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(len(hr.List()), 401, "Should contain all patterns which were not removed")
+}
+
 /**
 * Depth
  */
@@ -251,6 +576,16 @@ func BenchmarkLookupWeightDepth1(b *testing.B) {
 
 }
 
+func BenchmarkLookupParallelDepth7(b *testing.B) {
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			hrouterDepth.Lookup(DynPattern{Topic: "math", Cmd: "add", A: "1", B: "2", C: "foo", D: "11", E: "d23"})
+		}
+	})
+
+}
+
 func BenchmarkListDepth10000(b *testing.B) {
 
 	for n := 0; n < b.N; n++ {
@@ -329,6 +664,16 @@ func BenchmarkLookupWeightInsertion1(b *testing.B) {
 
 }
 
+func BenchmarkLookupParallelInsertion7(b *testing.B) {
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			hrouterInsertion.Lookup(DynPattern{Topic: "math", Cmd: "add", A: "1", B: "2", C: "foo", D: "11", E: "d23"})
+		}
+	})
+
+}
+
 func BenchmarkListInsertion100000(b *testing.B) {
 
 	for n := 0; n < b.N; n++ {
@@ -347,6 +692,88 @@ func BenchmarkAddInsertion(b *testing.B) {
 
 }
 
+// scaleRouter registers n distinct patterns of depth 2 to 4
+func scaleRouter(isDeep bool, n int) *Router {
+	hr := NewRouter(isDeep)
+
+	for i := 0; i < n; i++ {
+		p := DynPattern{Topic: fmt.Sprintf("topic%d", i%100), Cmd: fmt.Sprintf("cmd%d", i)}
+
+		if i%4 > 0 {
+			p.A = fmt.Sprint(i % 7)
+		}
+
+		if i%4 > 1 {
+			p.B = fmt.Sprint(i % 3)
+		}
+
+		hr.Add(p, i)
+	}
+
+	return hr
+}
+
+func benchmarkScale(b *testing.B, isDeep bool, n int) {
+	hr := scaleRouter(isDeep, n)
+	// box the pattern once, the conversion to interface{} is not part of the lookup
+	var p interface{} = DynPattern{Topic: "topic99", Cmd: fmt.Sprintf("cmd%d", n-1), A: fmt.Sprint((n - 1) % 7), B: fmt.Sprint((n - 1) % 3), C: "foo"}
+
+	if hr.Lookup(p) == nil {
+		b.Fatal("pattern not found")
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		hr.Lookup(p)
+	}
+}
+
+func BenchmarkLookupDepth10000(b *testing.B) {
+	benchmarkScale(b, true, 10000)
+}
+
+func BenchmarkLookupDepth100000(b *testing.B) {
+	benchmarkScale(b, true, 100000)
+}
+
+func BenchmarkLookupInsertion10000(b *testing.B) {
+	benchmarkScale(b, false, 10000)
+}
+
+func BenchmarkLookupInsertion100000(b *testing.B) {
+	benchmarkScale(b, false, 100000)
+}
+
+func BenchmarkLookupAll100000(b *testing.B) {
+	hr := scaleRouter(true, 100000)
+	var p interface{} = DynPattern{Topic: "topic99", Cmd: "cmd99999", A: "4", B: "0"}
+
+	if len(hr.LookupAll(p)) != 1 {
+		b.Fatal("pattern not found")
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		hr.LookupAll(p)
+	}
+}
+
+func BenchmarkLookupMap100000(b *testing.B) {
+	hr := scaleRouter(true, 100000)
+	p := map[string]interface{}{"topic": "topic99", "cmd": "cmd99999", "a": "3", "b": "0"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		hr.Lookup(p)
+	}
+}
+
 func init() {
 
 	for n := 0; n < 100; n++ {
@@ -375,3 +802,338 @@ func init() {
 		hrouterInsertion.Add(DynPattern{Topic: "order"}, "test1")
 	}
 }
+
+func TestConflicts(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(false)
+	hr.Add(DynPattern{Topic: "math"}, "test")
+	hr.Add(GlobPattern{Topic: "order", Cmd: Glob("*")}, "test1")
+
+	c := hr.Conflicts(DynPattern{Topic: "math"})
+	assert.Equal(len(c), 1, "Should have one conflict")
+	assert.Equal(c[0].Kind, ConflictDuplicate, "Should be a duplicate")
+
+	c = hr.Conflicts(DynPattern{Topic: "math", Cmd: "add"})
+	assert.Equal(len(c), 1, "Should have one conflict")
+	assert.Equal(c[0].Kind, ConflictShadowed, "Should be shadowed by the earlier pattern")
+
+	assert.Equal(len(hr.Conflicts(DynPattern{Topic: "order"})), 0, "Should not shadow the earlier wildcard pattern")
+
+	c = hr.ConflictsWithPriority(DynPattern{Topic: "order"}, 1)
+	assert.Equal(len(c), 1, "Should have one conflict")
+	assert.Equal(c[0].Kind, ConflictShadows, "Should shadow the wildcard pattern")
+
+	assert.Equal(len(hr.Conflicts(DynPattern{Topic: "user"})), 0, "Should have no conflicts")
+}
+
+func TestConflictsDepth(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(DynPattern{Topic: "math"}, "test")
+	hr.Add(DynPattern{Topic: "math", Cmd: "add"}, "test1")
+
+	assert.Equal(len(hr.Conflicts(DynPattern{Topic: "math", Cmd: "add", A: "1"})), 0, "Should allow more specific patterns")
+
+	c := hr.Conflicts(DynPattern{Topic: "math", A: "1"})
+	assert.Equal(len(c), 1, "Should have one conflict")
+	assert.Equal(c[0].Kind, ConflictAmbiguous, "Should be ambiguous with the pattern of equal weight")
+	assert.Equal(c[0].Pattern.Payload, "test1", "Should be `test1`")
+}
+
+func TestExplain(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(DynPattern{Topic: "math"}, "test")
+	hr.Add(DynPattern{Topic: "math", Cmd: "add"}, "test1")
+	hr.Add(GlobPattern{Topic: "math", Cmd: Glob("sub*")}, "test2")
+
+	e := hr.Explain(DynPattern{Topic: "math", Cmd: "sub"})
+
+	assert.Equal(e.Match.Payload, "test2", "Should select the deeper wildcard")
+	assert.Equal(len(e.Buckets), 2, "Should consult both buckets")
+	assert.Equal(e.Buckets[0].Keys, []string{"cmd", "topic"}, "Should consult the deepest bucket first")
+	assert.Equal(e.Buckets[0].Reason, "no pattern with the values of the request", "Should explain the miss")
+	assert.Equal(len(e.Candidates), 2, "Should report every candidate")
+	assert.Equal(e.Candidates[0].Reason, "selected", "Should be selected")
+	assert.Equal(e.Candidates[1].Reason, "matched", "Should match the shallower pattern")
+	assert.Equal(e.Match, hr.Lookup(DynPattern{Topic: "math", Cmd: "sub"}), "Should agree with Lookup")
+
+	e = hr.Explain(DynPattern{Cmd: "add"})
+	assert.Nil(e.Match, "Should not match")
+	assert.Equal(e.Buckets[0].Reason, "field topic is missing", "Should explain the missing field")
+}
+
+func TestPriority(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(DynPattern{Topic: "math", Cmd: "add"}, "test")
+	hr.AddWithPriority(DynPattern{Topic: "math"}, "test1", 1)
+
+	p := hr.Lookup(DynPattern{Topic: "math", Cmd: "add"})
+	assert.Equal(p.Payload, "test1", "Should prefer the higher priority over depth")
+
+	hr.AddWithPriority(GlobPattern{Topic: "math", Cmd: Glob("*")}, "test2", 2)
+
+	p = hr.Lookup(DynPattern{Topic: "math", Cmd: "add"})
+	assert.Equal(p.Payload, "test2", "Should prefer the higher priority over exact patterns")
+
+	var payloads []interface{}
+
+	for _, p := range hr.LookupAll(DynPattern{Topic: "math", Cmd: "add"}) {
+		payloads = append(payloads, p.Payload)
+	}
+
+	assert.Equal(payloads, []interface{}{"test2", "test1", "test"}, "Should be ordered by priority")
+}
+
+func TestPriorityInsertion(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(false)
+	hr.Add(DynPattern{Topic: "math"}, "test")
+	hr.AddWithPriority(DynPattern{Topic: "math", Cmd: "add"}, "test1", 1)
+
+	p := hr.Lookup(DynPattern{Topic: "math", Cmd: "add"})
+	assert.Equal(p.Payload, "test1", "Should prefer the higher priority over insertion order")
+
+	p = hr.Lookup(DynPattern{Topic: "math", Cmd: "sub"})
+	assert.Equal(p.Payload, "test", "Should be `test`")
+}
+
+type PricePattern struct {
+	Topic  string
+	Region interface{}
+	Amount interface{}
+}
+
+func TestSetMatcher(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(PricePattern{Topic: "price", Region: In("eu", "us")}, "test")
+	hr.Add(PricePattern{Topic: "price", Region: "eu"}, "test1")
+	hr.Add(PricePattern{Topic: "price", Region: Glob("*")}, "test2")
+
+	p := hr.Lookup(map[string]interface{}{"topic": "price", "region": "eu"})
+	assert.Equal(p.Payload, "test1", "Should prefer the exact value over the set")
+
+	p = hr.Lookup(map[string]interface{}{"topic": "price", "region": "us"})
+	assert.Equal(p.Payload, "test", "Should match a member of the set")
+
+	p = hr.Lookup(map[string]interface{}{"topic": "price", "region": "asia"})
+	assert.Equal(p.Payload, "test2", "Should fall back to the wildcard")
+
+	assert.Equal(len(hr.List()), 3, "Should list the set pattern once")
+	assert.True(hr.Remove(PricePattern{Topic: "price", Region: In("eu", "us")}), "Should remove the set pattern")
+
+	p = hr.Lookup(map[string]interface{}{"topic": "price", "region": "us"})
+	assert.Equal(p.Payload, "test2", "Should remove every member")
+}
+
+func TestSetDuplicateMembers(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(PricePattern{Topic: "price", Region: In("eu", "eu"), Amount: In(1, 1.0, int64(1))}, "test")
+
+	list := hr.LookupAll(map[string]interface{}{"topic": "price", "region": "eu", "amount": 1})
+	assert.Equal(len(list), 1, "Should return the pattern once")
+
+	e := hr.Explain(map[string]interface{}{"topic": "price", "region": "eu", "amount": 1.0})
+	assert.Equal(len(e.Candidates), 1, "Should index the pattern once")
+
+	assert.True(hr.Remove(PricePattern{Topic: "price", Region: In("eu", "eu"), Amount: In(1, 1.0, int64(1))}), "Should remove the pattern")
+	assert.Nil(hr.Lookup(map[string]interface{}{"topic": "price", "region": "eu", "amount": 1}), "Should remove every entry")
+}
+
+func TestRangeMatcher(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(PricePattern{Topic: "price", Amount: Between(0, 100)}, "test")
+	hr.Add(PricePattern{Topic: "price", Amount: AtLeast(100)}, "test1")
+	hr.Add(PricePattern{Topic: "price", Amount: 100}, "test2")
+	hr.Add(PricePattern{Topic: "price", Region: "eu", Amount: AtLeast(100)}, "test3")
+
+	p := hr.Lookup(map[string]interface{}{"topic": "price", "amount": 99.5})
+	assert.Equal(p.Payload, "test", "Should match the lower tier")
+
+	p = hr.Lookup(map[string]interface{}{"topic": "price", "amount": 250})
+	assert.Equal(p.Payload, "test1", "Should match the upper tier")
+
+	p = hr.Lookup(map[string]interface{}{"topic": "price", "amount": 100})
+	assert.Equal(p.Payload, "test2", "Should prefer the exact value over the range")
+
+	p = hr.Lookup(map[string]interface{}{"topic": "price", "region": "eu", "amount": 100})
+	assert.Equal(p.Payload, "test3", "Should prefer the deeper pattern")
+
+	assert.Nil(hr.Lookup(map[string]interface{}{"topic": "price", "amount": -1}), "Should not match below the range")
+	assert.Nil(hr.Lookup(map[string]interface{}{"topic": "price", "amount": "cheap"}), "Should not match strings")
+}
+
+func TestRangeMatcherInsertion(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(false)
+	hr.Add(PricePattern{Topic: "price", Amount: Between(0, 1000)}, "test")
+	hr.Add(PricePattern{Topic: "price", Amount: 100}, "test1")
+
+	p := hr.Lookup(PricePattern{Topic: "price", Amount: 100})
+	assert.Equal(p.Payload, "test", "Should prefer the earlier range")
+
+	c := hr.Conflicts(PricePattern{Topic: "price", Amount: 50})
+	assert.Equal(len(c), 1, "Should have one conflict")
+	assert.Equal(c[0].Kind, ConflictShadowed, "Should be shadowed by the range")
+}
+
+type TagPattern struct {
+	Topic  string
+	Cmd    string
+	A      int    `hemera:"match"`
+	Active bool   `hemera:"match"`
+	Note   string `hemera:"payload"`
+	Secret string `hemera:"-"`
+}
+
+func TestStructTags(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(TagPattern{Topic: "user", Cmd: "list", Note: "note", Secret: "secret"}, "test")
+	hr.Add(TagPattern{Topic: "user", Cmd: "list", A: 1, Active: true}, "test1")
+	hr.Add(DynPattern{Topic: "user", Cmd: "list"}, "test2")
+
+	p := hr.Lookup(TagPattern{Topic: "user", Cmd: "list", Note: "other"})
+	assert.Equal(p.Payload, "test", "Should match zero values and ignore payload fields")
+
+	p = hr.Lookup(map[string]interface{}{"topic": "user", "cmd": "list", "a": 0, "active": false})
+	assert.Equal(p.Payload, "test", "Should match zero values of a request")
+
+	p = hr.Lookup(map[string]interface{}{"topic": "user", "cmd": "list", "a": 1, "active": true})
+	assert.Equal(p.Payload, "test1", "Should be `test1`")
+
+	p = hr.Lookup(map[string]interface{}{"topic": "user", "cmd": "list", "a": 2})
+	assert.Equal(p.Payload, "test2", "Should not match other values")
+
+	p = hr.Lookup(map[string]interface{}{"topic": "user", "cmd": "list", "secret": "x"})
+	assert.Equal(p.Payload, "test", "Should ignore fields which are never routed")
+	assert.Equal(len(p.Fields), 2, "Should not index payload fields")
+}
+
+func exportHandler() {}
+
+func TestExportImport(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(map[string]interface{}{"topic": "order", "cmd": "create", "id": 9007199254740993}, "test")
+	hr.AddWithPriority(map[string]interface{}{"topic": "order", "cmd": Glob("ca*")}, exportHandler, 2)
+	hr.Add(map[string]interface{}{"topic": "order", "region": In("eu", "us"), "total": AtLeast(100)}, "test2")
+	hr.Add(map[string]interface{}{"topic": "order", "note": Zero, "id": MustRegexp("^[0-9]+$")}, "test3")
+
+	data, err := json.Marshal(hr)
+	assert.Nil(err, "Should export the router")
+
+	ir := NewRouter(true)
+	err = json.Unmarshal(data, ir)
+	assert.Nil(err, "Should import the table")
+
+	a, _ := hr.Export()
+	b, _ := ir.Export()
+	assert.Equal(a.Indexing, "depth", "Should be `depth`")
+	assert.Equal(b.Patterns[1].Handler, "github.com/hemerajs/go-hemera/router.exportHandler", "Should be the func name")
+
+	removed, added := Diff(a, b)
+	assert.Empty(removed, "Should be an equivalent table")
+	assert.Empty(added, "Should be an equivalent table")
+
+	p := ir.Lookup(map[string]interface{}{"topic": "order", "cmd": "create", "id": 9007199254740993})
+	assert.Equal(p.Payload, "test", "Should keep the precision of integers")
+
+	p = ir.Lookup(map[string]interface{}{"topic": "order", "cmd": "cancel"})
+	assert.Equal(p.Payload, "github.com/hemerajs/go-hemera/router.exportHandler", "Should be the handler name")
+	assert.Equal(p.Priority, 2, "Should keep the priority")
+
+	p = ir.Lookup(map[string]interface{}{"topic": "order", "region": "us", "total": 150})
+	assert.Equal(p.Payload, "test2", "Should import sets and ranges")
+
+	p = ir.Lookup(map[string]interface{}{"topic": "order", "id": "12"})
+	assert.Equal(p.Payload, "test3", "Should import zero and regexp matchers")
+
+	hr.Remove(map[string]interface{}{"topic": "order", "cmd": "create", "id": 9007199254740993})
+	a, _ = hr.Export()
+	removed, added = Diff(b, a)
+	assert.Equal(len(removed), 1, "Should report the removed pattern")
+	assert.Empty(added, "Should be empty")
+
+	err = NewRouter(false).Import(data, nil)
+	assert.NotNil(err, "Should reject a table of another indexing strategy")
+
+	err = NewRouter(true).Import(data, func(name string) (interface{}, error) {
+		return nil, fmt.Errorf("unknown handler %s", name)
+	})
+	assert.NotNil(err, "Should return the error of resolve")
+}
+
+func TestImportZeroRouter(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+	hr.Add(map[string]interface{}{"topic": "order"}, "test")
+	hr.Add(map[string]interface{}{"topic": "order", "cmd": "create"}, "test2")
+
+	data, _ := json.Marshal(hr)
+
+	var r Router
+	err := json.Unmarshal(data, &r)
+	assert.Nil(err, "Should import into a zero router")
+	assert.True(r.IsDeep, "Should take the indexing of the table")
+
+	p := r.Lookup(map[string]interface{}{"topic": "order", "cmd": "create"})
+	assert.Equal(p.Payload, "test2", "Should be test2")
+
+	var z Router
+	assert.Nil(z.Lookup(map[string]interface{}{"topic": "order"}), "Should be nil")
+	assert.Empty(z.List(), "Should be empty")
+
+	z.Add(map[string]interface{}{"topic": "order"}, "test")
+	p = z.Lookup(map[string]interface{}{"topic": "order"})
+	assert.Equal(p.Payload, "test", "Should add to a zero router")
+}
+
+func TestAddCheckedConcurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	hr := NewRouter(true)
+
+	var wg sync.WaitGroup
+	var added int32
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			err := hr.AddChecked(DynPattern{Topic: "math", Cmd: "add"}, "test", 0, func(conflicts []Conflict) error {
+				if len(conflicts) > 0 {
+					return fmt.Errorf("duplicate")
+				}
+				return nil
+			})
+
+			if err == nil {
+				atomic.AddInt32(&added, 1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(added, int32(1), "Should add the pattern once")
+	assert.Equal(len(hr.List()), 1, "Should contain one pattern")
+}
@@ -0,0 +1,383 @@
+package router
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// jsonNumber decodes JSON patterns without losing the precision of integers
+var jsonNumber = jsoniter.Config{UseNumber: true}.Froze()
+
+var (
+	matcherType    = reflect.TypeOf((*Matcher)(nil)).Elem()
+	jsonNumberType = reflect.TypeOf(json.Number(""))
+)
+
+type valueKind uint8
+
+const (
+	kindString valueKind = iota + 1
+	kindInt
+	kindUint
+	kindFloat
+	kindBool
+)
+
+// value is a normalized field value. Numbers are converted to a common type so
+// that a JSON float64 equals a Go int, integral numbers become int64 and all
+// others float64. Lookups compare values without boxing them into interfaces.
+type value struct {
+	kind valueKind
+	n    uint64
+	s    string
+}
+
+func stringValue(s string) value {
+	return value{kind: kindString, s: s}
+}
+
+func intValue(i int64) value {
+	return value{kind: kindInt, n: uint64(i)}
+}
+
+func uintValue(u uint64) value {
+	if u <= math.MaxInt64 {
+		return intValue(int64(u))
+	}
+
+	return value{kind: kindUint, n: u}
+}
+
+func floatValue(f float64) value {
+	if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		return intValue(int64(f))
+	}
+
+	return value{kind: kindFloat, n: math.Float64bits(f)}
+}
+
+func boolValue(b bool) value {
+	if b {
+		return value{kind: kindBool, n: 1}
+	}
+
+	return value{kind: kindBool}
+}
+
+func numberValue(n json.Number) value {
+	if i, err := n.Int64(); err == nil {
+		return intValue(i)
+	}
+
+	if f, err := n.Float64(); err == nil {
+		return floatValue(f)
+	}
+
+	return stringValue(n.String())
+}
+
+// iface returns the value as int64, uint64, float64, string or bool
+func (v value) iface() interface{} {
+	switch v.kind {
+	case kindString:
+		return v.s
+	case kindInt:
+		return int64(v.n)
+	case kindUint:
+		return v.n
+	case kindFloat:
+		return math.Float64frombits(v.n)
+	case kindBool:
+		return v.n == 1
+	}
+
+	return nil
+}
+
+const (
+	offset64 = 14695981039346656037
+	prime64  = 1099511628211
+)
+
+// hash continues the FNV-1a hash h with the value
+func (v value) hash(h uint64) uint64 {
+	h = (h ^ uint64(v.kind)) * prime64
+
+	if v.kind == kindString {
+		for i := 0; i < len(v.s); i++ {
+			h = (h ^ uint64(v.s[i])) * prime64
+		}
+		return h
+	}
+
+	for i := uint(0); i < 64; i += 8 {
+		h = (h ^ (v.n >> i & 0xff)) * prime64
+	}
+
+	return h
+}
+
+func hashValues(values []value) uint64 {
+	h := uint64(offset64)
+
+	for _, v := range values {
+		h = v.hash(h)
+	}
+
+	return h
+}
+
+func valuesEqual(a, b []value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// valueSink keeps the value of a single field
+type valueSink struct {
+	v  value
+	ok bool
+}
+
+func (s *valueSink) field(key string, v value) {
+	s.v, s.ok = v, true
+}
+
+func (s *valueSink) matcher(key string, m Matcher) {}
+
+// valueOf normalize a primitive value, zero values are valid
+func valueOf(i interface{}) (value, bool) {
+	switch v := i.(type) {
+	case string:
+		return stringValue(v), true
+	case bool:
+		return boolValue(v), true
+	case int:
+		return intValue(int64(v)), true
+	case int64:
+		return intValue(v), true
+	case float64:
+		return floatValue(v), true
+	}
+
+	if i == nil {
+		return value{}, false
+	}
+
+	// zero values are skipped by walk
+	if rv := reflect.ValueOf(i); rv.IsZero() {
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			return intValue(0), true
+		case reflect.String:
+			return stringValue(""), true
+		case reflect.Bool:
+			return boolValue(false), true
+		}
+		return value{}, false
+	}
+
+	s := &valueSink{}
+	walk(s, "value", i)
+
+	return s.v, s.ok
+}
+
+// joinKey returns the case-insensitive key of a field, nested fields are separated by a dot
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return strings.ToLower(name)
+	}
+
+	return prefix + "." + strings.ToLower(name)
+}
+
+// sink receives the fields of a pattern
+type sink interface {
+	field(key string, v value)
+	matcher(key string, m Matcher)
+}
+
+// walk emits the primitive values and matchers of a struct or a map. Zero values
+// and fields ending with `_` are skipped. Nested structs and maps are flattened
+// into dotted keys e.g `filter.role`.
+func walk(s sink, key string, val interface{}) {
+	switch v := val.(type) {
+	case nil:
+	case Matcher:
+		s.matcher(key, v)
+	case string:
+		if v != "" && key != "" {
+			s.field(key, stringValue(v))
+		}
+	case json.Number:
+		if v != "" && key != "" {
+			s.field(key, numberValue(v))
+		}
+	case float64:
+		if v != 0 && key != "" {
+			s.field(key, floatValue(v))
+		}
+	case int:
+		if v != 0 && key != "" {
+			s.field(key, intValue(int64(v)))
+		}
+	case bool:
+		if v && key != "" {
+			s.field(key, boolValue(v))
+		}
+	case map[string]interface{}:
+		walkMap(s, key, v)
+	default:
+		walkValue(s, key, reflect.ValueOf(val))
+	}
+}
+
+func walkMap(s sink, prefix string, m map[string]interface{}) {
+	for k, val := range m {
+		if !strings.HasSuffix(k, "_") {
+			walk(s, joinKey(prefix, k), val)
+		}
+	}
+}
+
+func walkValue(s sink, key string, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+
+		if e := v.Elem(); e.Type().Implements(matcherType) {
+			s.matcher(key, e.Interface().(Matcher))
+		} else {
+			walkValue(s, key, e)
+		}
+	case reflect.Struct:
+		walkStruct(s, key, v)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+
+		if m, ok := v.Interface().(map[string]interface{}); ok {
+			walkMap(s, key, m)
+			return
+		}
+
+		for it := v.MapRange(); it.Next(); {
+			if k := it.Key().String(); !strings.HasSuffix(k, "_") {
+				walkValue(s, joinKey(key, k), it.Value())
+			}
+		}
+	default:
+		if key == "" || !v.IsValid() || v.IsZero() {
+			return
+		}
+
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			s.field(key, intValue(v.Int()))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			s.field(key, uintValue(v.Uint()))
+		case reflect.Float32, reflect.Float64:
+			s.field(key, floatValue(v.Float()))
+		case reflect.String:
+			if v.Type() == jsonNumberType {
+				s.field(key, numberValue(json.Number(v.String())))
+			} else {
+				s.field(key, stringValue(v.String()))
+			}
+		case reflect.Bool:
+			s.field(key, boolValue(true))
+		}
+	}
+}
+
+type structField struct {
+	index   int
+	key     string
+	kind    reflect.Kind
+	matcher bool
+	// zero values are matched
+	match bool
+}
+
+// structCache holds the routable fields per struct type
+var structCache sync.Map
+
+func structFields(t reflect.Type) []structField {
+	if f, ok := structCache.Load(t); ok {
+		return f.([]structField)
+	}
+
+	fields := []structField{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("hemera")
+
+		if f.PkgPath != "" || f.Tag.Get("structs") == "-" || strings.HasSuffix(f.Name, "_") || tag == "-" || tag == "payload" {
+			continue
+		}
+
+		fields = append(fields, structField{
+			index:   i,
+			key:     strings.ToLower(f.Name),
+			kind:    f.Type.Kind(),
+			matcher: f.Type.Implements(matcherType) && f.Type.Kind() != reflect.Interface,
+			match:   tag == "match",
+		})
+	}
+
+	structCache.Store(t, fields)
+
+	return fields
+}
+
+func walkStruct(s sink, prefix string, v reflect.Value) {
+	for _, f := range structFields(v.Type()) {
+		fv := v.Field(f.index)
+
+		// fast path for plain strings
+		if f.kind == reflect.String && !f.matcher && !f.match && prefix == "" && fv.Type() != jsonNumberType {
+			if str := fv.String(); str != "" {
+				s.field(f.key, stringValue(str))
+			}
+			continue
+		}
+
+		if fv.IsZero() {
+			if f.match {
+				s.matcher(joinKey(prefix, f.key), Zero)
+			}
+			continue
+		}
+
+		key := f.key
+
+		if prefix != "" {
+			key = prefix + "." + f.key
+		}
+
+		if f.matcher {
+			s.matcher(key, fv.Interface().(Matcher))
+		} else {
+			walkValue(s, key, fv)
+		}
+	}
+}
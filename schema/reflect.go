@@ -0,0 +1,229 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Reflect returns the schema of a struct. The rules of a field are set by the
+// `validate` tag e.g. `validate:"required,min=1,max=10"`:
+//
+//	required   the field must be present and not the zero value
+//	min, max   bounds of a number, the length of a string or the items of a slice
+//	len        exact length of a string or items of a slice
+//	oneof      space separated list of allowed values
+//	pattern    regular expression a string must match, it must be the last rule
+//
+// Properties are named like the fields, fields tagged with `validate:"-"` or
+// `hemera:"-"` are skipped.
+func Reflect(v interface{}) (*Schema, error) {
+	t, ok := v.(reflect.Type)
+
+	if !ok {
+		t = reflect.TypeOf(v)
+	}
+
+	if t == nil {
+		return nil, fmt.Errorf("schema: can't reflect nil")
+	}
+
+	s, err := reflectType(t, map[reflect.Type]bool{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// MustReflect is like Reflect but panics when a tag is invalid
+func MustReflect(v interface{}) *Schema {
+	s, err := Reflect(v)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+func reflectType(t reflect.Type, visited map[reflect.Type]bool) (*Schema, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: TypeBoolean}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: TypeInteger}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: TypeNumber}, nil
+	case reflect.String:
+		if t == jsonNumberType {
+			return &Schema{Type: TypeNumber}, nil
+		}
+		return &Schema{Type: TypeString}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := reflectType(t.Elem(), visited)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &Schema{Type: TypeArray, Items: items}, nil
+	case reflect.Map:
+		return &Schema{Type: TypeObject}, nil
+	case reflect.Struct:
+		return reflectStruct(t, visited)
+	}
+
+	// interfaces accept any value
+	return &Schema{}, nil
+}
+
+func reflectStruct(t reflect.Type, visited map[reflect.Type]bool) (*Schema, error) {
+	s := &Schema{Type: TypeObject, nonZero: true}
+
+	// recursive types are validated up to the first repetition
+	if visited[t] {
+		return s, nil
+	}
+
+	visited[t] = true
+	defer delete(visited, t)
+
+	s.Properties = make(map[string]*Schema)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("validate")
+
+		if f.PkgPath != "" || tag == "-" || f.Tag.Get("hemera") == "-" {
+			continue
+		}
+
+		p, err := reflectType(f.Type, visited)
+
+		if err != nil {
+			return nil, err
+		}
+
+		required, err := applyRules(p, tag)
+
+		if err != nil {
+			return nil, fmt.Errorf("schema: field %s: %v", f.Name, err)
+		}
+
+		if required {
+			s.Required = append(s.Required, f.Name)
+		}
+
+		s.Properties[f.Name] = p
+	}
+
+	return s, nil
+}
+
+// applyRules sets the rules of the tag on the schema of a field
+func applyRules(s *Schema, tag string) (bool, error) {
+	required := false
+
+	for tag != "" {
+		var rule string
+
+		if strings.HasPrefix(tag, "pattern=") {
+			rule, tag = tag, ""
+		} else if i := strings.IndexByte(tag, ','); i >= 0 {
+			rule, tag = tag[:i], tag[i+1:]
+		} else {
+			rule, tag = tag, ""
+		}
+
+		name, arg := rule, ""
+
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+
+		switch name {
+		case "required":
+			required = true
+		case "min", "max", "len":
+			if err := applyBound(s, name, arg); err != nil {
+				return false, err
+			}
+		case "oneof":
+			for _, v := range strings.Fields(arg) {
+				if s.Type == TypeInteger || s.Type == TypeNumber {
+					f, err := strconv.ParseFloat(v, 64)
+
+					if err != nil {
+						return false, fmt.Errorf("invalid oneof value %q", v)
+					}
+
+					s.Enum = append(s.Enum, f)
+				} else {
+					s.Enum = append(s.Enum, v)
+				}
+			}
+		case "pattern":
+			s.Pattern = arg
+		case "":
+		default:
+			return false, fmt.Errorf("unknown rule %q", name)
+		}
+	}
+
+	return required, nil
+}
+
+func applyBound(s *Schema, name, arg string) error {
+	switch s.Type {
+	case TypeInteger, TypeNumber:
+		f, err := strconv.ParseFloat(arg, 64)
+
+		if err != nil || name == "len" {
+			return fmt.Errorf("invalid rule %s=%s", name, arg)
+		}
+
+		if name == "min" {
+			s.Minimum = &f
+		} else {
+			s.Maximum = &f
+		}
+
+		return nil
+	case TypeString, TypeArray:
+		n, err := strconv.Atoi(arg)
+
+		if err != nil {
+			return fmt.Errorf("invalid rule %s=%s", name, arg)
+		}
+
+		min, max := &s.MinLength, &s.MaxLength
+
+		if s.Type == TypeArray {
+			min, max = &s.MinItems, &s.MaxItems
+		}
+
+		if name != "max" {
+			*min = &n
+		}
+
+		if name != "min" {
+			*max = &n
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("rule %s is not supported by type %s", name, s.Type)
+}
@@ -0,0 +1,326 @@
+// Package schema validates decoded patterns against a subset of JSON Schema.
+// A schema is parsed from a JSON Schema document or reflected from the
+// `validate` tags of a struct.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+)
+
+type (
+	// Schema is the supported subset of a JSON Schema
+	Schema struct {
+		Type       string             `json:"type,omitempty"`
+		Properties map[string]*Schema `json:"properties,omitempty"`
+		Required   []string           `json:"required,omitempty"`
+		Items      *Schema            `json:"items,omitempty"`
+		Minimum    *float64           `json:"minimum,omitempty"`
+		Maximum    *float64           `json:"maximum,omitempty"`
+		MinLength  *int               `json:"minLength,omitempty"`
+		MaxLength  *int               `json:"maxLength,omitempty"`
+		MinItems   *int               `json:"minItems,omitempty"`
+		MaxItems   *int               `json:"maxItems,omitempty"`
+		Pattern    string             `json:"pattern,omitempty"`
+		Enum       []interface{}      `json:"enum,omitempty"`
+		re         *regexp.Regexp
+		// required fields must not be the zero value, like the required rule of a `validate` tag
+		nonZero bool
+	}
+	// FieldError describes a violated rule, Rule is the JSON Schema keyword
+	FieldError struct {
+		Field   string `json:"field"`
+		Rule    string `json:"rule"`
+		Message string `json:"message"`
+	}
+)
+
+// Parse decodes a JSON Schema document
+func Parse(data []byte) (*Schema, error) {
+	s := &Schema{}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("schema: %v", err)
+	}
+
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// MustParse is like Parse but panics when the document is invalid
+func MustParse(data []byte) *Schema {
+	s, err := Parse(data)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+func (s *Schema) compile() error {
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+
+		if err != nil {
+			return fmt.Errorf("schema: %v", err)
+		}
+
+		s.re = re
+	}
+
+	switch s.Type {
+	case "", TypeObject, TypeArray, TypeString, TypeNumber, TypeInteger, TypeBoolean:
+	default:
+		return fmt.Errorf("schema: unsupported type %q", s.Type)
+	}
+
+	for _, p := range s.Properties {
+		if err := p.compile(); err != nil {
+			return err
+		}
+	}
+
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+
+	return nil
+}
+
+// Validate returns all violated rules of the value, an empty result means the value is valid.
+// Properties are looked up case-insensitively like the fields of a decoded struct.
+func (s *Schema) Validate(v interface{}) []FieldError {
+	errs := []FieldError{}
+	s.validate("", v, &errs)
+	return errs
+}
+
+func (s *Schema) validate(path string, v interface{}, errs *[]FieldError) {
+	fail := func(rule, format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Field: path, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	rv := reflect.ValueOf(v)
+
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+
+	if !rv.IsValid() {
+		return
+	}
+
+	if s.Type != "" && !isType(s.Type, rv) {
+		fail("type", "must be of type %s", s.Type)
+		return
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, rv) {
+		fail("enum", "must be one of %v", s.Enum)
+	}
+
+	if n, ok := number(rv); ok {
+		if s.Minimum != nil && n < *s.Minimum {
+			fail("minimum", "must be at least %v", *s.Minimum)
+		}
+
+		if s.Maximum != nil && n > *s.Maximum {
+			fail("maximum", "must be at most %v", *s.Maximum)
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		str := rv.String()
+		l := len([]rune(str))
+
+		if s.MinLength != nil && l < *s.MinLength {
+			fail("minLength", "must have at least %d characters", *s.MinLength)
+		}
+
+		if s.MaxLength != nil && l > *s.MaxLength {
+			fail("maxLength", "must have at most %d characters", *s.MaxLength)
+		}
+
+		if s.Pattern != "" {
+			re := s.re
+
+			if re == nil {
+				re = regexp.MustCompile(s.Pattern)
+			}
+
+			if !re.MatchString(str) {
+				fail("pattern", "must match %s", s.Pattern)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if s.MinItems != nil && rv.Len() < *s.MinItems {
+			fail("minItems", "must have at least %d items", *s.MinItems)
+		}
+
+		if s.MaxItems != nil && rv.Len() > *s.MaxItems {
+			fail("maxItems", "must have at most %d items", *s.MaxItems)
+		}
+
+		if s.Items != nil {
+			for i := 0; i < rv.Len(); i++ {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), rv.Index(i).Interface(), errs)
+			}
+		}
+	case reflect.Map:
+		s.validateObject(path, rv, errs)
+	}
+}
+
+func (s *Schema) validateObject(path string, rv reflect.Value, errs *[]FieldError) {
+	if rv.Type().Key().Kind() != reflect.String {
+		return
+	}
+
+	fields := make(map[string]reflect.Value, rv.Len())
+
+	for it := rv.MapRange(); it.Next(); {
+		fields[strings.ToLower(it.Key().String())] = it.Value()
+	}
+
+	for _, name := range s.Required {
+		if f, ok := fields[strings.ToLower(name)]; !ok || isNil(f) || s.nonZero && isZero(f) {
+			*errs = append(*errs, FieldError{Field: join(path, name), Rule: "required", Message: "is required"})
+		}
+	}
+
+	names := make([]string, 0, len(s.Properties))
+
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if f, ok := fields[strings.ToLower(name)]; ok {
+			s.Properties[name].validate(join(path, name), f.Interface(), errs)
+		}
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	}
+
+	return false
+}
+
+// isZero checks if the value is the zero value of its type, a struct pattern sends zero values of unset fields
+func isZero(v reflect.Value) bool {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+
+	if n, ok := number(v); ok {
+		return n == 0
+	}
+
+	return v.IsZero()
+}
+
+func isType(t string, v reflect.Value) bool {
+	switch t {
+	case TypeObject:
+		return v.Kind() == reflect.Map || v.Kind() == reflect.Struct
+	case TypeArray:
+		return v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+	case TypeString:
+		return v.Kind() == reflect.String && v.Type() != jsonNumberType
+	case TypeBoolean:
+		return v.Kind() == reflect.Bool
+	case TypeNumber:
+		_, ok := number(v)
+		return ok
+	case TypeInteger:
+		n, ok := number(v)
+		return ok && n == math.Trunc(n)
+	}
+
+	return false
+}
+
+var jsonNumberType = reflect.TypeOf(json.Number(""))
+
+// number returns the value of a number or a json.Number as float64
+func number(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		if v.Type() == jsonNumberType {
+			f, err := json.Number(v.String()).Float64()
+			return f, err == nil
+		}
+	}
+
+	return 0, false
+}
+
+func inEnum(enum []interface{}, v reflect.Value) bool {
+	n, isNumber := number(v)
+
+	for _, e := range enum {
+		if isNumber {
+			if m, ok := number(reflect.ValueOf(e)); ok && m == n {
+				return true
+			}
+			continue
+		}
+
+		if str, ok := e.(string); ok && v.Kind() == reflect.String && str == v.String() {
+			return true
+		}
+
+		if reflect.DeepEqual(e, v.Interface()) {
+			return true
+		}
+	}
+
+	return false
+}
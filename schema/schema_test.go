@@ -0,0 +1,113 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Address struct {
+	City string `validate:"required"`
+}
+
+type Order struct {
+	Topic   string
+	Cmd     string  `validate:"required,oneof=create cancel"`
+	Amount  float64 `validate:"min=1,max=1000"`
+	Count   int
+	Code    string   `validate:"len=3,pattern=^[A-Z,]+$"`
+	Items   []string `validate:"min=1"`
+	Address *Address
+	Secret  string `hemera:"-" validate:"required"`
+	Meta    map[string]interface{}
+}
+
+func TestReflect(t *testing.T) {
+	assert := assert.New(t)
+
+	s, err := Reflect(Order{})
+	assert.Nil(err, "Should reflect the struct")
+	assert.Equal(s.Required, []string{"Cmd"}, "Should be required")
+	assert.Equal(s.Properties["Count"].Type, TypeInteger, "Should be `integer`")
+	assert.Equal(*s.Properties["Amount"].Minimum, 1.0, "Should be 1")
+	assert.Equal(*s.Properties["Code"].MaxLength, 3, "Should be 3")
+	assert.Equal(s.Properties["Code"].Pattern, "^[A-Z,]+$", "Should keep commas of the pattern")
+	assert.Equal(*s.Properties["Items"].MinItems, 1, "Should be 1")
+	assert.Equal(s.Properties["Address"].Required, []string{"City"}, "Should reflect nested structs")
+	assert.Nil(s.Properties["Secret"], "Should skip fields which are not sent")
+
+	_, err = Reflect(struct {
+		A bool `validate:"min=1"`
+	}{})
+	assert.NotNil(err, "Should reject unsupported rules")
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	s := MustReflect(Order{})
+
+	errs := s.Validate(map[string]interface{}{"topic": "order", "cmd": "create", "amount": 10.0, "code": "ABC", "items": []interface{}{"a"}})
+	assert.Empty(errs, "Should be valid")
+
+	errs = s.Validate(map[string]interface{}{
+		"Topic":   "order",
+		"Cmd":     "delete",
+		"Amount":  0.5,
+		"Count":   1.5,
+		"Code":    "abcd",
+		"Items":   []interface{}{1.0},
+		"Address": map[string]interface{}{},
+	})
+
+	rules := map[string]string{}
+
+	for _, e := range errs {
+		rules[e.Field] += e.Rule + " "
+	}
+
+	assert.Equal(rules, map[string]string{
+		"Cmd":          "enum ",
+		"Amount":       "minimum ",
+		"Count":        "type ",
+		"Code":         "maxLength pattern ",
+		"Items[0]":     "type ",
+		"Address.City": "required ",
+	}, "Should report every violated rule")
+
+	errs = s.Validate(map[string]interface{}{"topic": "order"})
+	assert.Equal(errs, []FieldError{{Field: "Cmd", Rule: "required", Message: "is required"}}, "Should be required")
+
+	errs = s.Validate(map[string]interface{}{"topic": "order", "cmd": ""})
+	assert.Equal(errs[0], FieldError{Field: "Cmd", Rule: "required", Message: "is required"}, "Should treat the zero value as missing")
+}
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	s, err := Parse([]byte(`{
+		"type": "object",
+		"required": ["a"],
+		"properties": {
+			"a": {"type": "integer", "minimum": 1},
+			"tags": {"type": "array", "maxItems": 1, "items": {"type": "string", "pattern": "^#"}}
+		}
+	}`))
+	assert.Nil(err, "Should parse the document")
+
+	errs := s.Validate(map[string]interface{}{"a": json.Number("2"), "tags": []interface{}{"#a"}})
+	assert.Empty(errs, "Should be valid")
+
+	errs = s.Validate(map[string]interface{}{"a": 0, "tags": []interface{}{"a", "#b"}})
+	assert.Equal(len(errs), 3, "Should be invalid")
+
+	errs = s.Validate("a")
+	assert.Equal(errs[0].Rule, "type", "Should be `type`")
+
+	_, err = Parse([]byte(`{"type": "date"}`))
+	assert.NotNil(err, "Should reject unsupported types")
+
+	_, err = Parse([]byte(`{"pattern": "("}`))
+	assert.NotNil(err, "Should reject invalid patterns")
+}
@@ -0,0 +1,65 @@
+package hemera
+
+import (
+	"testing"
+
+	"github.com/hemerajs/go-hemera/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+type ValidatedPattern struct {
+	Topic string
+	Cmd   string
+	A     int `validate:"required,min=1"`
+	B     int `validate:"max=10"`
+}
+
+func TestValidateTags(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h := newTestHemera(t, mt)
+
+	h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *ValidatedPattern, reply Reply) {
+		reply.Send(Response{Result: req.A + req.B})
+	}, ValidateTags())
+
+	res := &Response{}
+	ctx := h.Act(RequestPattern{Topic: "math", Cmd: "add", A: 1, B: 2}, res)
+	assert.Nil(ctx.Error, "Should have no error")
+	assert.Equal(res.Result, 3, "Should be 3")
+
+	ctx = h.Act(map[string]interface{}{"topic": "math", "cmd": "add", "b": 20}, res)
+	assert.True(IsValidationError(ctx.Error), "Should reply with a ValidationError")
+	assert.Equal(ctx.Error.(*Error).Details, []ErrorDetail{
+		{Field: "A", Rule: "required", Message: "is required"},
+		{Field: "B", Rule: "maximum", Message: "must be at most 10"},
+	}, "Should have a detail per field")
+
+	ctx = h.Act(RequestPattern{Topic: "math", Cmd: "add", B: 2}, res)
+	assert.Equal(ctx.Error.(*Error).Details, []ErrorDetail{
+		{Field: "A", Rule: "required", Message: "is required"},
+		{Field: "A", Rule: "minimum", Message: "must be at least 1"},
+	}, "Should treat unset fields of a struct pattern as missing")
+}
+
+func TestSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	mt := NewMemoryTransport()
+	defer mt.Close()
+
+	h := newTestHemera(t, mt)
+
+	_, err := h.Add(MathPattern{Topic: "math", Cmd: "add"}, func(req *RequestPattern, reply Reply) {
+		reply.Send(Response{Result: req.A + req.B})
+	}, Schema(schema.MustParse([]byte(`{"properties": {"a": {"type": "integer"}}}`))))
+	assert.Nil(err, "Should add the pattern")
+
+	res := &Response{}
+	ctx := h.Act(map[string]interface{}{"topic": "math", "cmd": "add", "a": 1.5}, res)
+	assert.True(IsValidationError(ctx.Error), "Should reject a fraction")
+	assert.Equal(ctx.Error.(*Error).Details[0].Field, "a", "Should be `a`")
+}
@@ -0,0 +1,76 @@
+package hemera
+
+import (
+	"time"
+
+	nats "github.com/nats-io/go-nats"
+)
+
+// ErrRequestTimeout is returned by a Transport when a request was not answered in time
+var ErrRequestTimeout = NewErrorSimple("transport: request timeout")
+
+type (
+	// Msg is a message which is delivered by a Transport
+	Msg struct {
+		Subject string
+		Reply   string
+		Data    []byte
+	}
+	// MsgHandler is the callback of a subscription
+	MsgHandler func(m *Msg)
+	// Subscription represents the interest in a subject
+	Subscription interface {
+		Unsubscribe() error
+	}
+	// Transport is the messaging system hemera is running on.
+	// Subscriptions with the same queue receive a message only once, an empty queue
+	// subscribes every subscriber.
+	Transport interface {
+		QueueSubscribe(subject, queue string, cb MsgHandler) (Subscription, error)
+		Request(subject string, data []byte, timeout time.Duration) (*Msg, error)
+		Publish(subject string, data []byte) error
+		Close()
+	}
+	natsTransport struct {
+		conn *nats.Conn
+	}
+)
+
+// NewNatsTransport create a Transport on top of a NATS connection
+func NewNatsTransport(conn *nats.Conn) Transport {
+	return &natsTransport{conn: conn}
+}
+
+func (t *natsTransport) QueueSubscribe(subject, queue string, cb MsgHandler) (Subscription, error) {
+	sub, err := t.conn.QueueSubscribe(subject, queue, func(m *nats.Msg) {
+		cb(&Msg{Subject: m.Subject, Reply: m.Reply, Data: m.Data})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+func (t *natsTransport) Request(subject string, data []byte, timeout time.Duration) (*Msg, error) {
+	m, err := t.conn.Request(subject, data, timeout)
+
+	if err == nats.ErrTimeout {
+		return nil, ErrRequestTimeout
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Msg{Subject: m.Subject, Reply: m.Reply, Data: m.Data}, nil
+}
+
+func (t *natsTransport) Publish(subject string, data []byte) error {
+	return t.conn.Publish(subject, data)
+}
+
+func (t *natsTransport) Close() {
+	t.conn.Close()
+}